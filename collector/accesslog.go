@@ -0,0 +1,282 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// routeRoots are the first path elements of Keystone API routes, requests to other paths are counted as "other" route
+// so that e.g. scanners probing random paths do not create new metrics
+var routeRoots = map[string]bool{"v3": true, "v2.0": true, "healthcheck": true}
+
+// routeCollections are path elements of Keystone API followed by ID or name of a single entity
+var routeCollections = map[string]bool{
+	"access_rules": true, "application_credentials": true, "consumers": true, "credentials": true,
+	"domains": true, "endpoints": true, "groups": true, "identity_providers": true, "implies": true,
+	"limits": true, "mappings": true, "policies": true, "projects": true, "protocols": true,
+	"regions": true, "registered_limits": true, "roles": true, "service_providers": true,
+	"services": true, "tags": true, "tenants": true, "tokens": true, "trusts": true, "users": true,
+	"websso": true,
+}
+
+// idPattern matches path elements which look like generated IDs: hex strings, UUIDs and numbers
+var idPattern = regexp.MustCompile(`^([0-9a-fA-F]{16,}|[0-9a-fA-F]{8}(-[0-9a-fA-F]{4}){3}-[0-9a-fA-F]{12}|[0-9]+)$`)
+
+// routeMethods are HTTP methods reported by their name, other methods are reported as "OTHER"
+var routeMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "OPTIONS": true,
+}
+
+// statusClasses are reported for every route, also with zero requests
+var statusClasses = []string{"1xx", "2xx", "3xx", "4xx", "5xx"}
+
+// routeKey identifies requests of single method and route template
+type routeKey struct {
+	method string
+	route  string
+}
+
+// routeRequests holds requests of single route read from access log during single collection
+type routeRequests struct {
+	total    int
+	statuses map[string]int
+	// latencies holds response times in milliseconds, of requests logged with response time only
+	latencies []float64
+}
+
+// accessRequests holds requests read from access log during single collection
+type accessRequests struct {
+	total       int
+	parseErrors int
+	routes      map[routeKey]*routeRequests
+}
+
+// add parses line of access log and counts request it holds. Lines are expected in Apache common or combined
+// log format, optionally with response time in microseconds (%D) following response size, e.g.
+// `10.0.0.1 - - [01/Jun/2017:10:00:00 +0000] "GET /v3/users/a1b2 HTTP/1.1" 200 1234 5678 "-" "curl/7.47.0"`.
+func (a *accessRequests) add(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	method, target, status, latency, err := parseAccessLine(line)
+	if err != nil {
+		a.parseErrors++
+		return
+	}
+	a.total++
+
+	key := routeKey{method: method, route: routeTemplate(target)}
+	requests, ok := a.routes[key]
+	if !ok {
+		requests = &routeRequests{statuses: map[string]int{}}
+		a.routes[key] = requests
+	}
+	requests.total++
+	requests.statuses[fmt.Sprintf("%dxx", status/100)]++
+	if latency >= 0 {
+		requests.latencies = append(requests.latencies, latency)
+	}
+}
+
+// errAccessLine is returned for line of access log in unknown format
+var errAccessLine = errors.New("unknown format of access log line")
+
+// parseAccessLine returns method, request target, status and response time in milliseconds (-1 when not logged)
+// of request logged in given line
+func parseAccessLine(line string) (string, string, int, float64, error) {
+	start := strings.Index(line, `"`)
+	if start < 0 {
+		return "", "", 0, 0, errAccessLine
+	}
+	end := strings.Index(line[start+1:], `"`)
+	if end < 0 {
+		return "", "", 0, 0, errAccessLine
+	}
+	end += start + 1
+
+	request := strings.Fields(line[start+1 : end])
+	if len(request) < 2 {
+		return "", "", 0, 0, errAccessLine
+	}
+	method := strings.ToUpper(request[0])
+	if !routeMethods[method] {
+		method = "OTHER"
+	}
+
+	fields := strings.Fields(line[end+1:])
+	if len(fields) < 2 {
+		return "", "", 0, 0, errAccessLine
+	}
+	status, err := strconv.Atoi(fields[0])
+	if err != nil || status < 100 || status > 599 {
+		return "", "", 0, 0, errAccessLine
+	}
+
+	latency := -1.0
+	if len(fields) > 2 {
+		if ms, ok := parseLatency(fields[2]); ok {
+			latency = ms
+		}
+	}
+	return method, request[1], status, latency, nil
+}
+
+// parseLatency returns response time in milliseconds logged as integer microseconds, e.g. "12345" or "12345(us)"
+// (Apache %D), or as fractional seconds, e.g. "0.012345" (eventlet WSGI server)
+func parseLatency(field string) (float64, bool) {
+	if strings.Contains(field, ".") {
+		seconds, err := strconv.ParseFloat(field, 64)
+		if err != nil || seconds < 0 {
+			return 0, false
+		}
+		return seconds * 1000, true
+	}
+
+	micros, err := strconv.ParseInt(strings.TrimSuffix(field, "(us)"), 10, 64)
+	if err != nil || micros < 0 {
+		return 0, false
+	}
+	return float64(micros) / 1000, true
+}
+
+// routeTemplate returns route of request target with IDs and names of single entities replaced by "ID"
+// and query left out, path elements are joined by "_" as "/" separates namespace elements,
+// e.g. "/v3/users/a1b2/projects?enabled" gives "v3_users_ID_projects"
+func routeTemplate(target string) string {
+	if i := strings.IndexAny(target, "?#"); i >= 0 {
+		target = target[:i]
+	}
+	path := strings.Trim(target, "/")
+	if path == "" {
+		return "root"
+	}
+
+	elements := strings.Split(path, "/")
+	if !routeRoots[elements[0]] {
+		return "other"
+	}
+	for i := 1; i < len(elements); i++ {
+		if routeCollections[elements[i-1]] || idPattern.MatchString(elements[i]) {
+			elements[i] = "ID"
+		}
+	}
+	return strings.Join(elements, "_")
+}
+
+// readAccessLog reads requests appended to access log since previous collection.
+// It must be called with collector mutex held, as the tailer is shared by collections.
+func (c *collector) readAccessLog(s *settings) (*accessRequests, error) {
+	if s.accessLogPath == "" {
+		return nil, errors.New("access_log_path is not set")
+	}
+	c.accessTail = tailerOf(c.accessTail, s.accessLogPath, c.accessState)
+
+	requests := &accessRequests{routes: map[routeKey]*routeRequests{}}
+	lines, err := c.accessTail.readLines()
+	for _, line := range lines {
+		requests.add(line)
+	}
+	return requests, err
+}
+
+// sortedRoutes returns routes read in the collection sorted by route and method
+func sortedRoutes(requests *accessRequests) []routeKey {
+	keys := []routeKey{}
+	for key := range requests.routes {
+		keys = append(keys, key)
+	}
+	sort.Sort(byRoute(keys))
+	return keys
+}
+
+// byRoute sorts route keys by route and method
+type byRoute []routeKey
+
+func (r byRoute) Len() int      { return len(r) }
+func (r byRoute) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r byRoute) Less(i, j int) bool {
+	if r[i].route != r[j].route {
+		return r[i].route < r[j].route
+	}
+	return r[i].method < r[j].method
+}
+
+// routeValues returns value computed from requests of every route read in the collection
+func routeValues(e *evaluation, compute func(*routeRequests) interface{}) []metricValue {
+	values := []metricValue{}
+	for _, key := range sortedRoutes(e.inventory.requests) {
+		values = append(values, metricValue{
+			dynamic: []string{key.method, key.route},
+			data:    compute(e.inventory.requests.routes[key]),
+		})
+	}
+	return values
+}
+
+// routeStatuses returns numbers of requests of every route per status class
+func routeStatuses(e *evaluation) []metricValue {
+	values := []metricValue{}
+	for _, key := range sortedRoutes(e.inventory.requests) {
+		requests := e.inventory.requests.routes[key]
+		for _, class := range statusClasses {
+			values = append(values, metricValue{
+				dynamic: []string{key.method, key.route, class},
+				data:    requests.statuses[class],
+			})
+		}
+	}
+	return values
+}
+
+// routeLatencies returns percentiles of response time of every route logged with response times
+func routeLatencies(e *evaluation) []metricValue {
+	values := []metricValue{}
+	for _, key := range sortedRoutes(e.inventory.requests) {
+		latencies := e.inventory.requests.routes[key].latencies
+		if len(latencies) == 0 {
+			continue
+		}
+		sort.Float64s(latencies)
+		for _, p := range e.settings.latencyPercentiles {
+			values = append(values, metricValue{
+				dynamic: []string{key.method, key.route, strconv.Itoa(p)},
+				data:    percentile(latencies, p),
+			})
+		}
+	}
+	return values
+}
+
+// percentile returns p-th percentile of sorted values using nearest-rank method
+func percentile(sorted []float64, p int) float64 {
+	rank := int(math.Ceil(float64(p) / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// accessLogEnabled tells if access log is configured
+func accessLogEnabled(s *settings) bool {
+	return s.accessLogPath != ""
+}
@@ -0,0 +1,232 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+const accessLines = `10.0.0.1 - - [01/Jun/2017:10:00:00 +0000] "POST /v3/auth/tokens HTTP/1.1" 201 7421 120000 "-" "python-keystoneclient"
+10.0.0.1 - - [01/Jun/2017:10:00:01 +0000] "POST /v3/auth/tokens HTTP/1.1" 401 114 40000 "-" "python-keystoneclient"
+10.0.0.2 - - [01/Jun/2017:10:00:02 +0000] "GET /v3/users/a1b2c3/projects?enabled=true HTTP/1.1" 200 512 8000 "-" "curl/7.47.0"
+10.0.0.2 - - [01/Jun/2017:10:00:03 +0000] "GET /v3/users/d4e5f6/projects HTTP/1.1" 503 0 2000 "-" "curl/7.47.0"
+10.0.0.3 - - [01/Jun/2017:10:00:04 +0000] "GET /v3/users/d4e5f6/projects HTTP/1.1" 200 512 "-" "curl/7.47.0"
+10.0.0.4 - - [01/Jun/2017:10:00:05 +0000] "GET /wp-login.php HTTP/1.1" 404 0 300 "-" "scanner"
+keystone-wsgi-public: starting
+`
+
+func TestRouteTemplate(t *testing.T) {
+	Convey("Given request targets", t, func() {
+		for target, route := range map[string]string{
+			"/":                                      "root",
+			"/v3":                                    "v3",
+			"/v3/auth/tokens":                        "v3_auth_tokens",
+			"/v3/users/a1b2c3/projects?enabled=true": "v3_users_ID_projects",
+			"/v3/projects/p1/users/u1/roles/r1":      "v3_projects_ID_users_ID_roles_ID",
+			"/v3/OS-FEDERATION/identity_providers/idp/protocols/saml2/auth": "v3_OS-FEDERATION_identity_providers_ID_protocols_ID_auth",
+			"/v3/auth/projects": "v3_auth_projects",
+			"/v2.0/tenants/" + "8f5cc3a2b8fc4c0a9c1e6c1e7d1b2c3d": "v2.0_tenants_ID",
+			"/v3/OS-INHERIT/0d6e3f4e-0a9b-4c1e-8d2f-3e4b5c6d7e8f": "v3_OS-INHERIT_ID",
+			"/wp-login.php": "other",
+		} {
+			Convey("Then "+target+" gives "+route, func() {
+				So(routeTemplate(target), ShouldEqual, route)
+			})
+		}
+	})
+}
+
+func TestAccessRequests(t *testing.T) {
+	Convey("Given access log lines with response time in different formats", t, func() {
+		requests := &accessRequests{routes: map[routeKey]*routeRequests{}}
+		for _, line := range []string{
+			// Apache %D, microseconds
+			`10.0.0.1 - - [01/Jun/2017:10:00:00 +0000] "GET /v3 HTTP/1.1" 200 512 12500 "-" "curl/7.47.0"`,
+			// Apache %D(us)
+			`10.0.0.1 - - [01/Jun/2017:10:00:01 +0000] "GET /v3 HTTP/1.1" 200 512 2500(us) "-" "curl/7.47.0"`,
+			// eventlet WSGI server, wall time in seconds
+			`10.0.0.1 - - [01/Jun/2017 10:00:02] "GET /v3 HTTP/1.1" 200 512 0.012345`,
+			`10.0.0.1 - - [01/Jun/2017 10:00:03] "GET /v3 HTTP/1.1" 200 512 1.5`,
+			// without response time
+			`10.0.0.1 - - [01/Jun/2017:10:00:04 +0000] "GET /v3 HTTP/1.1" 200 512 "-" "curl/7.47.0"`,
+		} {
+			requests.add(line)
+		}
+
+		Convey("Then response time is read in milliseconds from every format", func() {
+			So(requests.total, ShouldEqual, 5)
+			So(requests.parseErrors, ShouldEqual, 0)
+			latencies := requests.routes[routeKey{method: "GET", route: "v3"}].latencies
+			So(len(latencies), ShouldEqual, 4)
+			for i, ms := range []float64{12.5, 2.5, 12.345, 1500} {
+				So(latencies[i], ShouldAlmostEqual, ms)
+			}
+		})
+	})
+
+	Convey("Given access log lines", t, func() {
+		requests := &accessRequests{routes: map[routeKey]*routeRequests{}}
+		for _, line := range splitLines(accessLines) {
+			requests.add(line)
+		}
+
+		Convey("Then requests are counted per route", func() {
+			So(requests.total, ShouldEqual, 6)
+			So(requests.parseErrors, ShouldEqual, 1)
+			So(len(requests.routes), ShouldEqual, 3)
+
+			tokens := requests.routes[routeKey{method: "POST", route: "v3_auth_tokens"}]
+			So(tokens.total, ShouldEqual, 2)
+			So(tokens.statuses, ShouldResemble, map[string]int{"2xx": 1, "4xx": 1})
+			So(tokens.latencies, ShouldResemble, []float64{120, 40})
+
+			projects := requests.routes[routeKey{method: "GET", route: "v3_users_ID_projects"}]
+			So(projects.total, ShouldEqual, 3)
+			So(projects.latencies, ShouldResemble, []float64{8, 2})
+		})
+
+		Convey("and metric values are computed", func() {
+			e := &evaluation{inventory: &inventory{requests: requests}, settings: &settings{latencyPercentiles: []int{50, 100}}}
+
+			statuses := routeStatuses(e)
+			So(len(statuses), ShouldEqual, 3*len(statusClasses))
+			So(statuses[0].dynamic, ShouldResemble, []string{"GET", "other", "1xx"})
+			So(statuses[len(statusClasses)+1].dynamic, ShouldResemble, []string{"POST", "v3_auth_tokens", "2xx"})
+			So(statuses[len(statusClasses)+1].data, ShouldEqual, 1)
+
+			latencies := routeLatencies(e)
+			So(len(latencies), ShouldEqual, 6)
+			So(latencies[0].dynamic, ShouldResemble, []string{"GET", "other", "50"})
+			So(latencies[2].dynamic, ShouldResemble, []string{"POST", "v3_auth_tokens", "50"})
+			So(latencies[2].data, ShouldEqual, 40)
+			So(latencies[3].data, ShouldEqual, 120)
+		})
+	})
+}
+
+func TestPercentile(t *testing.T) {
+	Convey("Given sorted values", t, func() {
+		values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+		Convey("Then nearest-rank percentiles are returned", func() {
+			So(percentile(values, 1), ShouldEqual, 1)
+			So(percentile(values, 50), ShouldEqual, 5)
+			So(percentile(values, 90), ShouldEqual, 9)
+			So(percentile(values, 99), ShouldEqual, 10)
+			So(percentile(values, 100), ShouldEqual, 10)
+			So(percentile([]float64{7}, 50), ShouldEqual, 7)
+		})
+	})
+}
+
+func TestCollectAccessLog(t *testing.T) {
+	Convey("Given access log and config without reachable Keystone", t, func() {
+		dir, err := ioutil.TempDir("", "keystone")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "keystone_access.log")
+		So(ioutil.WriteFile(path, []byte(""), 0600), ShouldBeNil)
+
+		cfg := setupCfg("http://127.0.0.1:1", "me", "secret", "admin")
+		cfg["access_log_path"] = path
+
+		Convey("When metric types are listed", func() {
+			mts, err := New().GetMetricTypes(cfg)
+			So(err, ShouldBeNil)
+
+			Convey("Then access log metrics are listed", func() {
+				listed := 0
+				for _, m := range mts {
+					if m.Namespace.Strings()[3] == "access_log" {
+						listed++
+					}
+				}
+				So(listed, ShouldEqual, 6)
+			})
+		})
+
+		Convey("When tasks sharing the collector collect access log metrics at the same time", func() {
+			total := plugin.Metric{
+				Namespace: plugin.NewNamespace(vendor, fs, name, "access_log", "requests_count"),
+				Config:    cfg}
+
+			c := New()
+			_, err := c.CollectMetrics([]plugin.Metric{total})
+			So(err, ShouldBeNil)
+			So(ioutil.WriteFile(path, []byte(accessLines), 0600), ShouldBeNil)
+
+			var done sync.WaitGroup
+			counts := make(chan int, 4)
+			for i := 0; i < 4; i++ {
+				done.Add(1)
+				go func() {
+					defer done.Done()
+					mts, err := c.CollectMetrics([]plugin.Metric{total})
+					if err == nil && len(mts) == 1 {
+						counts <- mts[0].Data.(int)
+					}
+				}()
+			}
+			done.Wait()
+			close(counts)
+
+			Convey("Then every request is counted once", func() {
+				sum := 0
+				for count := range counts {
+					sum += count
+				}
+				So(sum, ShouldEqual, 6)
+			})
+		})
+
+		Convey("When access log metrics are collected", func() {
+			ratio := plugin.Metric{
+				Namespace: plugin.NewNamespace(vendor, fs, name, "access_log", "routes", "*", "*", "error_ratio"),
+				Config:    cfg}
+			total := plugin.Metric{
+				Namespace: plugin.NewNamespace(vendor, fs, name, "access_log", "requests_count"),
+				Config:    cfg}
+
+			c := New()
+			_, err := c.CollectMetrics([]plugin.Metric{ratio, total})
+			So(err, ShouldBeNil)
+			So(ioutil.WriteFile(path, []byte(accessLines), 0600), ShouldBeNil)
+			mts, err := c.CollectMetrics([]plugin.Metric{ratio, total})
+
+			Convey("Then requests logged since previous collection are counted without Keystone", func() {
+				So(err, ShouldBeNil)
+				values := map[string]interface{}{}
+				for _, m := range mts {
+					values[m.Namespace.String()] = m.Data
+				}
+				So(values, ShouldResemble, map[string]interface{}{
+					"/intel/openstack/keystone/access_log/routes/GET/other/error_ratio":                0.0,
+					"/intel/openstack/keystone/access_log/routes/GET/v3_users_ID_projects/error_ratio": 1.0 / 3,
+					"/intel/openstack/keystone/access_log/routes/POST/v3_auth_tokens/error_ratio":      0.0,
+					"/intel/openstack/keystone/access_log/requests_count":                              6,
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,158 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// cadfTypeURI is the type of CADF events
+const cadfTypeURI = "http://schemas.dmtf.org/cloud/audit/1.0/event"
+
+// cadfEvent is CADF event sent by Keystone as audit notification, only fields used by the collector are decoded
+type cadfEvent struct {
+	TypeURI   string        `json:"typeURI"`
+	Action    string        `json:"action"`
+	Outcome   string        `json:"outcome"`
+	Initiator cadfInitiator `json:"initiator"`
+}
+
+// cadfInitiator is the initiator of CADF event, Keystone adds scope of its token when known
+type cadfInitiator struct {
+	ID       string `json:"id"`
+	DomainID string `json:"domain_id"`
+}
+
+// auditKey identifies counted CADF events
+type auditKey struct {
+	// action is "authenticate", "created" or "deleted"
+	action string
+	// outcome is "success" or "failure", set for authentication only
+	outcome string
+	// resource is type of created or deleted resource, e.g. "user", "project" or "role"
+	resource string
+	domain   string
+}
+
+// auditEvents holds numbers of CADF events read from audit log during single collection
+type auditEvents struct {
+	total       int
+	parseErrors int
+	counts      map[auditKey]int
+}
+
+// unknownDomain is reported for events whose initiator domain is not known, e.g. failed authentication
+const unknownDomain = "unknown"
+
+// add parses line of audit log and counts event it holds. Keystone writes notifications either as plain JSON
+// or prefixed by log record header, so JSON is looked for from the first brace. Lines without JSON are skipped,
+// as well as notifications which are not CADF events.
+func (a *auditEvents) add(line string) {
+	start := strings.Index(line, "{")
+	if start < 0 {
+		return
+	}
+
+	var notification struct {
+		cadfEvent
+		Payload *cadfEvent `json:"payload"`
+	}
+	if err := json.Unmarshal([]byte(line[start:]), &notification); err != nil {
+		a.parseErrors++
+		return
+	}
+
+	event := &notification.cadfEvent
+	if notification.Payload != nil {
+		event = notification.Payload
+	}
+	if event.TypeURI != cadfTypeURI {
+		return
+	}
+	a.total++
+
+	domain := event.Initiator.DomainID
+	if domain == "" {
+		domain = unknownDomain
+	}
+
+	if event.Action == "authenticate" {
+		if event.Outcome == "success" || event.Outcome == "failure" {
+			a.counts[auditKey{action: event.Action, outcome: event.Outcome, domain: domain}]++
+		}
+		return
+	}
+
+	// e.g. "created.user" or "deleted.project"
+	parts := strings.SplitN(event.Action, ".", 2)
+	if len(parts) == 2 && (parts[0] == "created" || parts[0] == "deleted") && event.Outcome == "success" {
+		a.counts[auditKey{action: parts[0], resource: parts[1], domain: domain}]++
+	}
+}
+
+// readAuditLog reads CADF events appended to audit log since previous collection.
+// It must be called with collector mutex held, as the tailer is shared by collections.
+func (c *collector) readAuditLog(s *settings) (*auditEvents, error) {
+	if s.auditLogPath == "" {
+		return nil, errors.New("audit_log_path is not set")
+	}
+	c.auditTail = tailerOf(c.auditTail, s.auditLogPath, c.auditState)
+
+	events := &auditEvents{counts: map[auditKey]int{}}
+	lines, err := c.auditTail.readLines()
+	for _, line := range lines {
+		events.add(line)
+	}
+	return events, err
+}
+
+// auditCounts returns values of metric counting events with given action and outcome, per resource (when
+// resource is the first dynamic element) and initiator domain. Every domain seen in the collection is reported,
+// also with zero events.
+func auditCounts(e *evaluation, action, outcome string, perResource bool) []metricValue {
+	keys := map[auditKey]bool{}
+	for key := range e.inventory.audit.counts {
+		if (key.action == "authenticate") != (action == "authenticate") {
+			continue
+		}
+		keys[auditKey{action: action, outcome: outcome, resource: key.resource, domain: key.domain}] = true
+	}
+
+	dynamic := []string{}
+	byDynamic := map[string]auditKey{}
+	for key := range keys {
+		id := key.domain
+		if perResource {
+			id = key.resource + "/" + key.domain
+		}
+		dynamic = append(dynamic, id)
+		byDynamic[id] = key
+	}
+	sort.Strings(dynamic)
+
+	values := []metricValue{}
+	for _, id := range dynamic {
+		key := byDynamic[id]
+		value := metricValue{dynamic: []string{key.domain}, data: e.inventory.audit.counts[key]}
+		if perResource {
+			value.dynamic = []string{key.resource, key.domain}
+		}
+		values = append(values, value)
+	}
+	return values
+}
@@ -0,0 +1,193 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+const auditLines = `2017-06-01 10:00:00.123 1234 INFO oslo.messaging.notification.identity.authenticate [-] {"event_type": "identity.authenticate", "payload": {"typeURI": "http://schemas.dmtf.org/cloud/audit/1.0/event", "action": "authenticate", "outcome": "success", "initiator": {"id": "u1", "domain_id": "default"}}, "priority": "INFO"}
+{"event_type": "identity.authenticate", "payload": {"typeURI": "http://schemas.dmtf.org/cloud/audit/1.0/event", "action": "authenticate", "outcome": "failure", "initiator": {"id": "u2", "domain_id": "default"}}}
+{"event_type": "identity.authenticate", "payload": {"typeURI": "http://schemas.dmtf.org/cloud/audit/1.0/event", "action": "authenticate", "outcome": "failure", "initiator": {"id": "8f5cc3a2"}}}
+{"typeURI": "http://schemas.dmtf.org/cloud/audit/1.0/event", "action": "created.user", "outcome": "success", "initiator": {"id": "admin", "domain_id": "default"}}
+{"event_type": "identity.project.deleted", "payload": {"typeURI": "http://schemas.dmtf.org/cloud/audit/1.0/event", "action": "deleted.project", "outcome": "success", "initiator": {"id": "admin", "domain_id": "ldap"}}}
+{"event_type": "identity.user.created", "payload": {"resource_info": "u3"}}
+2017-06-01 10:00:01.000 1234 INFO keystone.common.wsgi [-] GET http://keystone:5000/v3/
+{"event_type": "identity.authenticate", "payload": {"typeURI":
+`
+
+// splitLines returns lines of text ended by new line
+func splitLines(text string) []string {
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+func TestAuditEvents(t *testing.T) {
+	Convey("Given audit log lines", t, func() {
+		events := &auditEvents{counts: map[auditKey]int{}}
+
+		Convey("When lines are parsed", func() {
+			for _, line := range splitLines(auditLines) {
+				events.add(line)
+			}
+
+			Convey("Then CADF events are counted", func() {
+				So(events.total, ShouldEqual, 5)
+				So(events.parseErrors, ShouldEqual, 1)
+				So(events.counts, ShouldResemble, map[auditKey]int{
+					{action: "authenticate", outcome: "success", domain: "default"}: 1,
+					{action: "authenticate", outcome: "failure", domain: "default"}: 1,
+					{action: "authenticate", outcome: "failure", domain: "unknown"}: 1,
+					{action: "created", resource: "user", domain: "default"}:        1,
+					{action: "deleted", resource: "project", domain: "ldap"}:        1,
+				})
+			})
+
+			Convey("and metric values are computed per domain and resource", func() {
+				e := &evaluation{inventory: &inventory{audit: events}}
+
+				failures := auditCounts(e, "authenticate", "failure", false)
+				So(len(failures), ShouldEqual, 2)
+				So(failures[0].dynamic, ShouldResemble, []string{"default"})
+				So(failures[0].data, ShouldEqual, 1)
+				So(failures[1].dynamic, ShouldResemble, []string{"unknown"})
+
+				successes := auditCounts(e, "authenticate", "success", false)
+				So(len(successes), ShouldEqual, 2)
+				So(successes[1].data, ShouldEqual, 0)
+
+				created := auditCounts(e, "created", "", true)
+				So(len(created), ShouldEqual, 2)
+				So(created[0].dynamic, ShouldResemble, []string{"project", "ldap"})
+				So(created[0].data, ShouldEqual, 0)
+				So(created[1].dynamic, ShouldResemble, []string{"user", "default"})
+				So(created[1].data, ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func TestCollectAuditLog(t *testing.T) {
+	Convey("Given audit log and config without reachable Keystone", t, func() {
+		dir, err := ioutil.TempDir("", "keystone")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "notifications.log")
+		So(ioutil.WriteFile(path, []byte(""), 0600), ShouldBeNil)
+
+		cfg := setupCfg("http://127.0.0.1:1", "me", "secret", "admin")
+		cfg["audit_log_path"] = path
+		cfg["state_dir"] = filepath.Join(dir, "state")
+
+		Convey("When metric types are listed", func() {
+			mts, err := New().GetMetricTypes(cfg)
+			So(err, ShouldBeNil)
+
+			Convey("Then audit metrics are listed", func() {
+				listed := 0
+				for _, m := range mts {
+					if m.Namespace.Strings()[3] == "audit" {
+						listed++
+					}
+				}
+				So(listed, ShouldEqual, 6)
+			})
+		})
+
+		Convey("When tasks sharing the collector collect audit metrics at the same time", func() {
+			total := plugin.Metric{
+				Namespace: plugin.NewNamespace(vendor, fs, name, "audit", "events_count"),
+				Config:    cfg}
+
+			c := New()
+			_, err := c.CollectMetrics([]plugin.Metric{total})
+			So(err, ShouldBeNil)
+			So(ioutil.WriteFile(path, []byte(auditLines), 0600), ShouldBeNil)
+
+			var done sync.WaitGroup
+			counts := make(chan int, 4)
+			for i := 0; i < 4; i++ {
+				done.Add(1)
+				go func() {
+					defer done.Done()
+					mts, err := c.CollectMetrics([]plugin.Metric{total})
+					if err == nil && len(mts) == 1 {
+						counts <- mts[0].Data.(int)
+					}
+				}()
+			}
+			done.Wait()
+			close(counts)
+
+			Convey("Then every event is counted once", func() {
+				sum := 0
+				for count := range counts {
+					sum += count
+				}
+				So(sum, ShouldEqual, 5)
+			})
+		})
+
+		Convey("When audit metrics are collected", func() {
+			failures := plugin.Metric{
+				Namespace: plugin.NewNamespace(vendor, fs, name, "audit", "authenticate", "*", "failure_count"),
+				Config:    cfg}
+			total := plugin.Metric{
+				Namespace: plugin.NewNamespace(vendor, fs, name, "audit", "events_count"),
+				Config:    cfg}
+
+			c := New()
+			_, err := c.CollectMetrics([]plugin.Metric{failures, total})
+			So(err, ShouldBeNil)
+			So(ioutil.WriteFile(path, []byte(auditLines), 0600), ShouldBeNil)
+			mts, err := c.CollectMetrics([]plugin.Metric{failures, total})
+
+			Convey("Then events written since previous collection are counted without Keystone", func() {
+				So(err, ShouldBeNil)
+				values := map[string]interface{}{}
+				for _, m := range mts {
+					values[m.Namespace.String()] = m.Data
+				}
+				So(values, ShouldResemble, map[string]interface{}{
+					"/intel/openstack/keystone/audit/authenticate/default/failure_count": 1,
+					"/intel/openstack/keystone/audit/authenticate/unknown/failure_count": 1,
+					"/intel/openstack/keystone/audit/events_count":                       5,
+				})
+			})
+
+			Convey("and read offset survives restart", func() {
+				appendFile, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+				So(err, ShouldBeNil)
+				_, err = appendFile.WriteString(splitLines(auditLines)[1] + "\n")
+				So(err, ShouldBeNil)
+				So(appendFile.Close(), ShouldBeNil)
+
+				mts, err := New().CollectMetrics([]plugin.Metric{total})
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 1)
+				So(mts[0].Data, ShouldEqual, 1)
+			})
+		})
+	})
+}
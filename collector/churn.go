@@ -0,0 +1,209 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/types"
+)
+
+// entity whose IDs are compared between collections
+type trackedEntity struct {
+	name    string
+	sources []string
+	ids     func(inv *inventory) []string
+
+	// anonymous entities have no IDs of their own, their changes are counted but not listed
+	anonymous bool
+}
+
+// trackedEntities lists entities whose changes are reported. Services and endpoints are otherwise
+// gathered only once, so they are fetched again in every collection which reports their changes.
+var trackedEntities = []trackedEntity{
+	{
+		name:    "tenants",
+		sources: []string{srcTenants},
+		ids: func(inv *inventory) []string {
+			ids := []string{}
+			for _, tenant := range inv.tenants {
+				ids = append(ids, tenant.ID)
+			}
+			return ids
+		},
+	},
+	{
+		name:    "users",
+		sources: []string{srcUsers},
+		ids: func(inv *inventory) []string {
+			ids := []string{}
+			for _, user := range inv.users {
+				ids = append(ids, user.ID)
+			}
+			return ids
+		},
+	},
+	{
+		name:    "services",
+		sources: []string{srcServices, srcFreshCatalog},
+		ids: func(inv *inventory) []string {
+			ids := []string{}
+			for _, service := range inv.services {
+				ids = append(ids, service.ID)
+			}
+			return ids
+		},
+	},
+	{
+		name:    "endpoints",
+		sources: []string{srcEndpoints, srcFreshCatalog},
+		ids: func(inv *inventory) []string {
+			ids := []string{}
+			for _, endpoint := range inv.endpoints {
+				ids = append(ids, endpoint.ID)
+			}
+			return ids
+		},
+	},
+	{
+		name:    "revocation_events",
+		sources: []string{srcRevocations},
+		ids: func(inv *inventory) []string {
+			ids := []string{}
+			for _, event := range inv.revocations {
+				ids = append(ids, revocationID(event))
+			}
+			return ids
+		},
+		anonymous: true,
+	},
+}
+
+// change holds IDs of entities added and removed since previous collection
+type change struct {
+	added   []string
+	removed []string
+}
+
+// detectChanges compares IDs of entities gathered in this collection with IDs remembered from the previous one,
+// and remembers them for the next collection. Entities which were not gathered are neither compared nor remembered,
+// entities seen for the first time have no changes reported.
+func (c *collector) detectChanges(inv *inventory, needed map[string]bool) map[string]*change {
+	if c.previous == nil {
+		c.previous = map[string]map[string]bool{}
+	}
+
+	changes := map[string]*change{}
+	for _, entity := range trackedEntities {
+		if !needed[entity.sources[0]] || !inv.available(entity.sources[0]) {
+			continue
+		}
+
+		current := map[string]bool{}
+		for _, id := range entity.ids(inv) {
+			current[id] = true
+		}
+
+		if previous, ok := c.previous[entity.name]; ok {
+			changes[entity.name] = diffIDs(previous, current)
+		}
+		c.previous[entity.name] = current
+	}
+	return changes
+}
+
+// diffIDs returns sorted IDs which were added to and removed from set of IDs
+func diffIDs(previous, current map[string]bool) *change {
+	ch := &change{added: []string{}, removed: []string{}}
+	for id := range current {
+		if !previous[id] {
+			ch.added = append(ch.added, id)
+		}
+	}
+	for id := range previous {
+		if !current[id] {
+			ch.removed = append(ch.removed, id)
+		}
+	}
+	sort.Strings(ch.added)
+	sort.Strings(ch.removed)
+	return ch
+}
+
+// churnCount declares metric with number of entities with given name added or removed since previous collection
+func churnCount(entityName string, added bool) metricDef {
+	var entity trackedEntity
+	for _, tracked := range trackedEntities {
+		if tracked.name == entityName {
+			entity = tracked
+		}
+	}
+
+	kind := "removed"
+	if added {
+		kind = "added"
+	}
+	return metricDef{
+		namespace:   plugin.NewNamespace(vendor, fs, name, entity.name+"_"+kind+"_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of " + strings.Replace(entity.name, "_", " ", -1) + " " + kind + " since previous collection",
+		sources:     entity.sources,
+		compute: func(e *evaluation) []metricValue {
+			ch, ok := e.changes[entity.name]
+			if !ok {
+				return []metricValue{}
+			}
+			if added {
+				return []metricValue{{data: len(ch.added)}}
+			}
+			return []metricValue{{data: len(ch.removed)}}
+		},
+	}
+}
+
+// churnEvent returns values of metric reporting changed IDs, one per entity which changed since previous collection
+func churnEvent(e *evaluation) []metricValue {
+	values := []metricValue{}
+	for _, entity := range trackedEntities {
+		ch, ok := e.changes[entity.name]
+		if !ok || entity.anonymous || len(ch.added)+len(ch.removed) == 0 {
+			continue
+		}
+
+		tags := map[string]string{}
+		addTag(tags, "added", joinDistinct(ch.added))
+		addTag(tags, "removed", joinDistinct(ch.removed))
+		values = append(values, metricValue{
+			dynamic: []string{entity.name},
+			data:    len(ch.added) + len(ch.removed),
+			tags:    tags,
+		})
+	}
+	return values
+}
+
+// revocationID identifies revocation event, which has no ID, by hash of all its fields
+func revocationID(event types.RevocationEvent) string {
+	data, _ := json.Marshal(event)
+	hash := sha1.Sum(data)
+	return hex.EncodeToString(hash[:])
+}
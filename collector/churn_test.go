@@ -0,0 +1,128 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/types"
+)
+
+func TestDetectChanges(t *testing.T) {
+	Convey("Given collector which gathered users and tenants", t, func() {
+		c := &collector{}
+		needed := map[string]bool{srcUsers: true, srcTenants: true}
+		first := &inventory{
+			users:   []types.User{{ID: "u1"}, {ID: "u2"}, {ID: "u3"}},
+			tenants: []types.Tenant{{ID: "t1"}},
+		}
+		So(c.detectChanges(first, needed), ShouldBeEmpty)
+
+		Convey("When users are added and removed before next collection", func() {
+			second := &inventory{
+				users:   []types.User{{ID: "u1"}, {ID: "u4"}, {ID: "u5"}},
+				tenants: []types.Tenant{{ID: "t1"}},
+			}
+			changes := c.detectChanges(second, needed)
+
+			Convey("Then changed IDs are reported per entity", func() {
+				So(changes["users"].added, ShouldResemble, []string{"u4", "u5"})
+				So(changes["users"].removed, ShouldResemble, []string{"u2", "u3"})
+				So(changes["tenants"].added, ShouldBeEmpty)
+				So(changes["tenants"].removed, ShouldBeEmpty)
+				So(changes, ShouldNotContainKey, "services")
+			})
+
+			Convey("and counts and event are computed from the changes", func() {
+				e := &evaluation{inventory: second, changes: changes}
+				ns := plugin.NewNamespace(vendor, fs, name, "users_added_count")
+				values, err := findMetric(ns).values(e, ns)
+				So(err, ShouldBeNil)
+				So(values[0].data, ShouldEqual, 2)
+
+				ns = plugin.NewNamespace(vendor, fs, name, "changes", "*", "event")
+				values, err = findMetric(ns).values(e, ns)
+				So(err, ShouldBeNil)
+				So(len(values), ShouldEqual, 1)
+				So(values[0].dynamic, ShouldResemble, []string{"users"})
+				So(values[0].data, ShouldEqual, 4)
+				So(values[0].tags, ShouldResemble, map[string]string{"added": "u4,u5", "removed": "u2,u3"})
+			})
+
+			Convey("and the latest IDs are compared with next collection", func() {
+				changes := c.detectChanges(second, needed)
+				So(changes["users"].added, ShouldBeEmpty)
+				So(changes["users"].removed, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When users could not be gathered in next collection", func() {
+			failed := &inventory{errs: map[string]error{srcUsers: errSourceFailed}}
+			changes := c.detectChanges(failed, needed)
+
+			Convey("Then changes of users are not reported and previous IDs are kept", func() {
+				So(changes, ShouldNotContainKey, "users")
+				So(len(c.previous["users"]), ShouldEqual, 3)
+			})
+		})
+	})
+}
+
+func TestRevocationChanges(t *testing.T) {
+	Convey("Given collector which gathered revocation events", t, func() {
+		c := &collector{}
+		needed := map[string]bool{srcRevocations: true}
+		issued := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+		first := &inventory{
+			revocations: []types.RevocationEvent{{UserID: "u1", IssuedBefore: issued}, {AuditID: "a1", IssuedBefore: issued}},
+		}
+		So(c.detectChanges(first, needed), ShouldBeEmpty)
+
+		Convey("When events are added and pruned before next collection", func() {
+			second := &inventory{
+				revocations: []types.RevocationEvent{
+					{AuditID: "a1", IssuedBefore: issued},
+					{UserID: "u1", IssuedBefore: issued.Add(time.Hour)},
+					{ProjectID: "p1", IssuedBefore: issued},
+				},
+			}
+			e := &evaluation{inventory: second, changes: c.detectChanges(second, needed)}
+
+			Convey("Then events are counted by their content", func() {
+				ns := plugin.NewNamespace(vendor, fs, name, "revocation_events_added_count")
+				values, err := findMetric(ns).values(e, ns)
+				So(err, ShouldBeNil)
+				So(values[0].data, ShouldEqual, 2)
+
+				ns = plugin.NewNamespace(vendor, fs, name, "revocation_events_removed_count")
+				values, err = findMetric(ns).values(e, ns)
+				So(err, ShouldBeNil)
+				So(values[0].data, ShouldEqual, 1)
+			})
+
+			Convey("and their hashes are not listed in change event", func() {
+				ns := plugin.NewNamespace(vendor, fs, name, "changes", "*", "event")
+				values, err := findMetric(ns).values(e, ns)
+				So(err, ShouldBeNil)
+				So(values, ShouldBeEmpty)
+			})
+		})
+	})
+}
@@ -15,237 +15,336 @@ limitations under the License.
 package collector
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/rackspace/gophercloud"
+	log "github.com/sirupsen/logrus"
 
-	"github.com/intelsdi-x/snap/control/plugin"
-	"github.com/intelsdi-x/snap/control/plugin/cpolicy"
-
-	"github.com/intelsdi-x/snap-plugin-utilities/config"
-	"github.com/intelsdi-x/snap-plugin-utilities/str"
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
 
 	openstackintel "github.com/intelsdi-x/snap-plugin-collector-keystone/openstack"
 	"github.com/intelsdi-x/snap-plugin-collector-keystone/types"
-	"github.com/intelsdi-x/snap/core"
 )
 
 const (
 	name    = "keystone"
-	version = 3
-	plgtype = plugin.CollectorPluginType
+	version = 4
 	vendor  = "intel"
 	fs      = "openstack"
 )
 
-var keystoneMetrics = []string{
-	"total_tenants_count",
-	"total_users_count",
-	"total_services_count",
-	"total_endpoints_count",
-}
-
 // New creates initialized instance of Glance collector
 func New() *collector {
 	return &collector{}
 }
 
 // GetMetricTypes returns list of available metric types
-// It returns error in case retrieval was not successful
-func (c *collector) GetMetricTypes(cfg plugin.ConfigType) ([]plugin.MetricType, error) {
-	mts := []plugin.MetricType{}
-	domain_name := ""
-	domain_id := ""
-	items, err := config.GetConfigItems(cfg, "admin_endpoint", "admin_user", "admin_password", "admin_tenant")
+// Users count is exposed once with dynamic tenant_name element, which is expanded during collection,
+// so tenants created after the task was started are collected as well.
+// Metrics read from local files are listed only when their file is configured.
+// It returns error in case configuration is not valid
+func (c *collector) GetMetricTypes(cfg plugin.Config) ([]plugin.Metric, error) {
+	s, err := newSettings(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	mts := []plugin.Metric{}
+	for _, m := range metricDefs {
+		if m.enabled != nil && !m.enabled(s) {
+			continue
+		}
+		mts = append(mts, plugin.Metric{
+			Namespace:   m.namespace,
+			Unit:        m.unit,
+			Description: m.description,
+		})
+	}
+	return mts, nil
+}
+
+// CollectMetrics returns list of requested metric values
+// Metrics whose data source failed are left out and failures of all sources are aggregated in CollectionError,
+// which is returned only when none of requested metrics could be collected.
+// Collections are serialized, as tasks sharing the plugin instance may request metrics at the same time.
+func (c *collector) CollectMetrics(metricTypes []plugin.Metric) ([]plugin.Metric, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.collectMetrics(metricTypes)
+}
+
+// collectMetrics returns list of requested metric values, it must be called with mutex held
+func (c *collector) collectMetrics(metricTypes []plugin.Metric) ([]plugin.Metric, error) {
+	if len(metricTypes) == 0 {
+		return nil, nil
+	}
+
+	s, err := newSettings(metricTypes[0].Config)
 	if err != nil {
 		return nil, err
 	}
 
-	endpoint := items["admin_endpoint"].(string)
-	user := items["admin_user"].(string)
-	password := items["admin_password"].(string)
-	tenant := items["admin_tenant"].(string)
-	dom_name, _ := config.GetConfigItem(cfg, "domain_name")
-	dom_id, _ := config.GetConfigItem(cfg, "domain_id")
-	if dom_name != nil {
-		domain_name = dom_name.(string)
+	ctx, cancel := s.collectionContext()
+	defer cancel()
+
+	namespaces := []plugin.Namespace{}
+	for _, metricType := range metricTypes {
+		namespaces = append(namespaces, metricType.Namespace)
 	}
-	if dom_id != nil {
-		domain_id = dom_id.(string)
+
+	c.useCloud(s)
+	c.loadState(s)
+
+	needed := neededSources(namespaces)
+	inv := c.fetch(ctx, s, needed)
+	errs := inv.collectionError()
+
+	e := &evaluation{
+		collector: c,
+		inventory: inv,
+		settings:  s,
+		waited:    c.limiterWaited(),
+		now:       time.Now(),
+		changes:   c.detectChanges(inv, needed),
 	}
+	c.saveState(s)
 
-	if c.provider == nil {
-		c.provider, err = openstackintel.Authenticate(endpoint, user, password, tenant, domain_name, domain_id)
+	metrics := []plugin.Metric{}
+	for _, metricType := range metricTypes {
+		ns := metricType.Namespace
+		m := findMetric(ns)
+		if m == nil {
+			errs.add(ns.String(), errors.New("unknown metric"))
+			continue
+		}
+
+		values, err := m.values(e, ns)
 		if err != nil {
+			if err != errSourceFailed {
+				errs.add(ns.String(), err)
+			}
+			continue
+		}
+
+		dynamic := dynamicElements(m.namespace)
+		for _, value := range values {
+			tags := map[string]string{}
+			addTag(tags, "endpoint", s.endpoint)
+			addTag(tags, "cloud", s.cloudName)
+			for key, tag := range value.tags {
+				tags[key] = tag
+			}
+
+			metrics = append(metrics, plugin.Metric{
+				Timestamp:   time.Now(),
+				Namespace:   value.namespace(ns, dynamic),
+				Data:        value.data,
+				Tags:        tags,
+				Unit:        m.unit,
+				Description: m.description,
+			})
+		}
+	}
+
+	c.lastErr = errs.errOrNil()
+	if err := c.lastErr; err != nil {
+		if len(metrics) == 0 {
 			return nil, err
 		}
+		log.WithField("plugin", name).Warn(err)
 	}
 
-	// retrieve list of all available tenants for provided endpoint, user and password
-	allTenants, err := openstackintel.GetAllTenants(c.provider)
-	if err != nil {
-		return nil, err
+	return metrics, nil
+}
+
+// useCloud drops provider, catalog and churn state cached for other Keystone or credentials than given ones,
+// as tasks collecting from different clouds may share the plugin instance.
+// Changed password of the same user requires authentication only.
+func (c *collector) useCloud(s *settings) {
+	if s.password != c.password {
+		c.password = s.password
+		c.provider = nil
 	}
 
-	// Generate available namespace from tenants (user counts per tenant)
-	for _, tenant := range allTenants {
-		mts = append(mts, plugin.MetricType{
-			Namespace_: core.NewNamespace(vendor, fs, name, tenant.Name, "users_count"),
-			Config_:    cfg.ConfigDataNode,
-		})
+	cloud := s.cloudID()
+	if cloud == c.cloud {
+		return
 	}
 
-	// Generate available namespace from keystone metrics
-	for _, keystoneMetric := range keystoneMetrics {
-		mts = append(mts, plugin.MetricType{
-			Namespace_: core.NewNamespace(vendor, fs, name, keystoneMetric),
-			Config_:    cfg.ConfigDataNode,
-		})
+	c.cloud = cloud
+	c.guard = c.guardOf(s.endpoint)
+	c.provider = nil
+	c.auth = nil
+	c.endpoints = nil
+	c.services = nil
+	c.previous = nil
+	c.stateLoaded = false
+}
+
+// guardOf returns rate limiter and circuit breaker of given Keystone endpoint, so that failures of one Keystone
+// do not suspend requests to other one and each Keystone has its own rate limit
+func (c *collector) guardOf(endpoint string) *guard {
+	if c.guards == nil {
+		c.guards = map[string]*guard{}
 	}
-	return mts, nil
+	if _, ok := c.guards[endpoint]; !ok {
+		c.guards[endpoint] = &guard{}
+	}
+	return c.guards[endpoint]
 }
 
-// CollectMetrics returns list of requested metric values
-// It returns error in case retrieval was not successful
-func (c *collector) CollectMetrics(metricTypes []plugin.MetricType) ([]plugin.MetricType, error) {
-	domain_name := ""
-	domain_id := ""
-	items, err := config.GetConfigItems(metricTypes[0], "admin_endpoint", "admin_user", "admin_password", "admin_tenant")
-	if err != nil {
-		return nil, err
+// authenticate creates provider client shared by all requests sent to Keystone
+func (c *collector) authenticate(ctx context.Context, s *settings) error {
+	if c.provider != nil {
+		return nil
 	}
 
-	endpoint := items["admin_endpoint"].(string)
-	user := items["admin_user"].(string)
-	password := items["admin_password"].(string)
-	tenant := items["admin_tenant"].(string)
-	dom_name, _ := config.GetConfigItem(metricTypes[0], "domain_name")
-	dom_id, _ := config.GetConfigItem(metricTypes[0], "domain_id")
-	if dom_name != nil {
-		domain_name = dom_name.(string)
+	var err error
+	c.provider, err = openstackintel.Authenticate(ctx, s.endpoint, s.user, s.password, s.tenant, s.tenantID,
+		s.domainName, s.domainID, c.clientOptions(s)...)
+	if err != nil {
+		return err
 	}
-	if dom_id != nil {
-		domain_id = dom_id.(string)
+
+	c.auth = &authState{IdentityEndpoint: c.provider.IdentityEndpoint, AuthenticatedAt: time.Now().UTC()}
+	return nil
+}
+
+// clientOptions creates rate limiter, retry policy and circuit breaker for provider client.
+// Rate limiter and circuit breaker are created once per Keystone, so they survive failed authentication attempts.
+func (c *collector) clientOptions(s *settings) []openstackintel.Option {
+	g := c.guardOf(s.endpoint)
+	opts := []openstackintel.Option{}
+
+	if tlsConfig := s.tlsConfig(); tlsConfig != nil {
+		opts = append(opts, openstackintel.WithTLSConfig(tlsConfig))
 	}
 
-	if c.provider == nil {
-		c.provider, err = openstackintel.Authenticate(endpoint, user, password, tenant, domain_name, domain_id)
-		if err != nil {
-			return nil, err
-		}
+	if s.requestTimeout > 0 {
+		opts = append(opts, openstackintel.WithRequestTimeout(s.requestTimeout))
 	}
 
-	var done sync.WaitGroup
-	errCh := make(chan error, 4)
+	if g.limiter == nil && s.maxRequests > 0 {
+		g.limiter = openstackintel.NewRateLimiter(s.maxRequests, s.burst)
+	}
+	if g.limiter != nil {
+		opts = append(opts, openstackintel.WithRateLimiter(g.limiter))
+	}
 
-	// collect services and endpoint only once
-	if c.endpoints == nil {
-		done.Add(1)
-		go func() {
-			var err error
-			if c.endpoints, err = openstackintel.GetAllEndpoints(c.provider); err != nil {
-				errCh <- err
-			}
-			done.Done()
-		}()
-	}
-	if c.services == nil {
-		done.Add(1)
-		go func() {
-			var err error
-			if c.services, err = openstackintel.GetAllServices(c.provider); err != nil {
-				errCh <- err
-			}
-			done.Done()
-		}()
+	if s.maxRetries > 0 {
+		opts = append(opts, openstackintel.WithRetry(openstackintel.RetryPolicy{
+			MaxRetries: s.maxRetries,
+			BaseDelay:  s.retryBaseDelay,
+			MaxDelay:   s.retryMaxDelay,
+		}))
 	}
 
-	done.Add(2)
-	tenantList := []types.Tenant{}
-	go func() {
-		var err error
-		if tenantList, err = openstackintel.GetAllTenants(c.provider); err != nil {
-			errCh <- err
-		}
-		done.Done()
-	}()
-
-	userList := []types.User{}
-	go func() {
-		var err error
-		if userList, err = openstackintel.GetAllUsers(c.provider); err != nil {
-			errCh <- err
-		}
-		done.Done()
-	}()
+	if g.breaker == nil && s.breakerThreshold > 0 {
+		g.breaker = openstackintel.NewCircuitBreaker(s.breakerThreshold, s.breakerResetTimeout)
+	}
+	if g.breaker != nil {
+		opts = append(opts, openstackintel.WithCircuitBreaker(g.breaker))
+	}
 
-	done.Wait()
-	close(errCh)
+	return opts
+}
 
-	if err = <-errCh; err != nil {
-		return nil, err
+// collectionContext returns context which is done when collection_timeout passes
+func (s *settings) collectionContext() (context.Context, context.CancelFunc) {
+	if s.collectionTimeout <= 0 {
+		return context.WithCancel(context.Background())
 	}
+	return context.WithTimeout(context.Background(), s.collectionTimeout)
+}
 
-	tenantUsers, err := openstackintel.GetUsersPerTenant(c.provider, tenantList)
-	if err != nil {
-		return nil, err
+// timeoutError replaces err with more descriptive one when collection was cancelled because of collection_timeout
+func timeoutError(ctx context.Context, err error) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("collection_timeout exceeded: %v", err)
 	}
+	return err
+}
 
-	metrics := []plugin.MetricType{}
-	for _, metricType := range metricTypes {
-		namespace := metricType.Namespace().Strings()
-		metric := plugin.MetricType{
-			Timestamp_: time.Now(),
-			Namespace_: metricType.Namespace(),
-		}
-
-		if str.Contains(keystoneMetrics, namespace[3]) {
-			switch namespace[3] {
-			case "total_tenants_count":
-				metric.Data_ = len(tenantList)
-			case "total_users_count":
-				metric.Data_ = len(userList)
-			case "total_services_count":
-				metric.Data_ = len(c.services)
-			case "total_endpoints_count":
-				metric.Data_ = len(c.endpoints)
-			}
-		} else {
-			tenantName := namespace[3]
-			val, ok := tenantUsers[tenantName]
-			if ok {
-				metric.Data_ = val
-			}
-		}
+// breakerState returns state of circuit breaker of current Keystone, closed when breaker is disabled
+func (c *collector) breakerState() openstackintel.CircuitState {
+	if c.guard == nil || c.guard.breaker == nil {
+		return openstackintel.CircuitClosed
+	}
+	return c.guard.breaker.State()
+}
 
-		metrics = append(metrics, metric)
+// limiterWaited returns time requests to current Keystone spent waiting for rate limiter since previous call
+func (c *collector) limiterWaited() time.Duration {
+	if c.guard == nil || c.guard.limiter == nil {
+		return 0
 	}
 
-	return metrics, nil
+	waited := c.guard.limiter.Waited()
+	delta := waited - c.guard.lastWaited
+	c.guard.lastWaited = waited
+
+	return delta
 }
 
 // GetConfigPolicy returns config policy
 // It returns error in case retrieval was not successful
-func (c *collector) GetConfigPolicy() (*cpolicy.ConfigPolicy, error) {
-	cp := cpolicy.New()
-	return cp, nil
+func (c *collector) GetConfigPolicy() (plugin.ConfigPolicy, error) {
+	policy, err := configPolicy()
+	if err != nil {
+		return plugin.ConfigPolicy{}, err
+	}
+	return *policy, nil
 }
 
-// Meta returns plugin meta data
-func Meta() *plugin.PluginMeta {
-	return plugin.NewPluginMeta(
-		name,
-		version,
-		plgtype,
-		[]string{plugin.SnapGOBContentType},
-		[]string{plugin.SnapGOBContentType},
-		plugin.RoutingStrategy(plugin.StickyRouting),
-	)
+// Meta returns plugin name, version and options the plugin is started with
+func Meta() (string, int, []plugin.MetaOpt) {
+	return name, version, []plugin.MetaOpt{plugin.RoutingStrategy(plugin.StickyRouter)}
 }
 
 type collector struct {
+	// mutex guards state of the collector, which is changed by every collection
+	mutex sync.Mutex
+
+	// cloud identifies Keystone and credentials which provider and cached state belong to,
+	// password is the one provider authenticated with
+	cloud    string
+	password string
+
 	provider  *gophercloud.ProviderClient
+	lastErr   error
 	endpoints []types.Endpoint
 	services  []types.Service
+
+	// guards hold rate limiter and circuit breaker by Keystone endpoint, guard is the one of current cloud
+	guards map[string]*guard
+	guard  *guard
+
+	// previous holds IDs of entities gathered in previous collection, by entity name
+	previous map[string]map[string]bool
+
+	// auth describes the last successful authentication
+	auth *authState
+
+	// stateLoaded is set once state saved by previous run of the plugin was restored
+	stateLoaded bool
+
+	// auditTail reads audit log, auditState is its position restored from state
+	auditTail  *tailer
+	auditState *tailState
+
+	// accessTail reads access log, accessState is its position restored from state
+	accessTail  *tailer
+	accessState *tailState
+}
+
+// guard limits and suspends requests sent to a single Keystone
+type guard struct {
+	limiter    *openstackintel.RateLimiter
+	breaker    *openstackintel.CircuitBreaker
+	lastWaited time.Duration
 }
@@ -15,18 +15,17 @@ limitations under the License.
 package collector
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
 	"testing"
 
 	th "github.com/rackspace/gophercloud/testhelper"
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/stretchr/testify/suite"
 
-	"github.com/intelsdi-x/snap/control/plugin"
-	"github.com/intelsdi-x/snap/core"
-	"github.com/intelsdi-x/snap/core/cdata"
-	"github.com/intelsdi-x/snap/core/ctypes"
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
 
 	"github.com/intelsdi-x/snap-plugin-utilities/str"
 )
@@ -72,16 +71,115 @@ func (s *CollectorSuite) TestGetMetricTypes() {
 			Convey("and proper metric types are returned", func() {
 				metricNames := []string{}
 				for _, m := range mts {
-					metricNames = append(metricNames, m.Namespace().String())
+					metricNames = append(metricNames, m.Namespace.String())
 				}
 
-				So(len(mts), ShouldEqual, 6)
-				So(str.Contains(metricNames, "/intel/openstack/keystone/demo/users_count"), ShouldBeTrue)
-				So(str.Contains(metricNames, "/intel/openstack/keystone/admin/users_count"), ShouldBeTrue)
+				So(len(mts), ShouldEqual, 45)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/*/users_count"), ShouldBeTrue)
 				So(str.Contains(metricNames, "/intel/openstack/keystone/total_tenants_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/project_tags/*/projects_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/project_tags/*/users_count"), ShouldBeTrue)
 				So(str.Contains(metricNames, "/intel/openstack/keystone/total_users_count"), ShouldBeTrue)
 				So(str.Contains(metricNames, "/intel/openstack/keystone/total_endpoints_count"), ShouldBeTrue)
 				So(str.Contains(metricNames, "/intel/openstack/keystone/total_services_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/tenants_added_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/endpoints_removed_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/changes/*/event"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/rate_limit_wait_ms"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/circuit_breaker_state"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/users/inactive/*/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/users/never_active_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/users/password_expiring_within/*/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/users/password_expired_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/users/disabled_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/credentials/types/*/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/credentials/types/*/projects/*/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/credentials/application/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/credentials/application/users/*/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/credentials/application/expiring_within/*/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/credentials/application/expired_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/credentials/application/no_expiry_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/total_trusts_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/total_revocation_events_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/revocation_events_added_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/oldest_revocation_event_age_s"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/trusts_expired_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/trusts_without_expiry_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/trusts_with_impersonation_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/total_policies_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/limits/services/*/registered_limits_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/limits/services/*/limits_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/limits/services/*/override_count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/limits/projects/*/services/*/resources/*/limit"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/domains/*/users/inactive/*/count"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/domains/*/users/never_active_count"), ShouldBeTrue)
+			})
+
+			Convey("and metric types have units and descriptions", func() {
+				for _, m := range mts {
+					So(m.Description, ShouldNotBeEmpty)
+					if m.Namespace.String() == "/intel/openstack/keystone/rate_limit_wait_ms" {
+						So(m.Unit, ShouldEqual, "ms")
+					}
+				}
+			})
+
+			Convey("and tenant name is dynamic element of users count", func() {
+				for _, m := range mts {
+					if m.Namespace.String() == "/intel/openstack/keystone/*/users_count" {
+						So(dynamicElements(m.Namespace), ShouldResemble, []int{3})
+						So(m.Namespace[3].Name, ShouldEqual, "tenant_name")
+					}
+				}
+			})
+		})
+	})
+
+	Convey("Given config with invalid endpoint", s.T(), func() {
+		cfg := setupCfg("keystone", "me", "secret", "admin")
+
+		Convey("When GetMetricTypes() is called", func() {
+			_, err := New().GetMetricTypes(cfg)
+
+			Convey("Then error should be reported", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsDynamic() {
+	Convey("Given users count metric type with tenant name wildcard", s.T(), func() {
+		cfg := setupCfg(th.Endpoint(), "me", "secret", "admin")
+		m1 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone").
+				AddDynamicElement("tenant_name", "name of tenant").
+				AddStaticElement("users_count"),
+			Config: cfg}
+
+		Convey("When CollectMetrics() is called", func() {
+			collector := New()
+
+			mts, err := collector.CollectMetrics([]plugin.Metric{m1})
+
+			Convey("Then no error should be reported", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("and users count of every tenant is returned", func() {
+				metricNames := map[string]interface{}{}
+				for _, m := range mts {
+					metricNames[m.Namespace.String()] = m.Data
+					So(m.Namespace[3].Name, ShouldEqual, "tenant_name")
+				}
+
+				So(len(mts), ShouldEqual, 2)
+				So(metricNames["/intel/openstack/keystone/demo/users_count"], ShouldEqual, 3)
+				So(metricNames["/intel/openstack/keystone/admin/users_count"], ShouldNotBeNil)
+			})
+
+			Convey("and wildcard of requested metric type is not modified", func() {
+				So(m1.Namespace[3].Value, ShouldEqual, "*")
 			})
 		})
 	})
@@ -90,17 +188,17 @@ func (s *CollectorSuite) TestGetMetricTypes() {
 func (s *CollectorSuite) TestCollectMetrics() {
 	Convey("Given set of metric types", s.T(), func() {
 		cfg := setupCfg(th.Endpoint(), "me", "secret", "admin")
-		m1 := plugin.MetricType{
-			Namespace_: core.NewNamespace("intel", "openstack", "keystone", "demo", "users_count"),
-			Config_:    cfg.ConfigDataNode}
-		m2 := plugin.MetricType{
-			Namespace_: core.NewNamespace("intel", "openstack", "keystone", "total_services_count"),
-			Config_:    cfg.ConfigDataNode}
+		m1 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "demo", "users_count"),
+			Config:    cfg}
+		m2 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "total_services_count"),
+			Config:    cfg}
 
 		Convey("When ColelctMetrics() is called", func() {
 			collector := New()
 
-			mts, err := collector.CollectMetrics([]plugin.MetricType{m1, m2})
+			mts, err := collector.CollectMetrics([]plugin.Metric{m1, m2})
 
 			Convey("Then no error should be reported", func() {
 				So(err, ShouldBeNil)
@@ -109,8 +207,8 @@ func (s *CollectorSuite) TestCollectMetrics() {
 			Convey("and proper metric types are returned", func() {
 				metricNames := map[string]interface{}{}
 				for _, m := range mts {
-					ns := m.Namespace().String()
-					metricNames[ns] = m.Data()
+					ns := m.Namespace.String()
+					metricNames[ns] = m.Data
 				}
 				fmt.Println(metricNames)
 				So(len(mts), ShouldEqual, 2)
@@ -127,13 +225,438 @@ func (s *CollectorSuite) TestCollectMetrics() {
 	})
 }
 
-func setupCfg(endpoint, user, password, tenant string) plugin.ConfigType {
-	node := cdata.NewNode()
-	node.AddItem("admin_endpoint", ctypes.ConfigValueStr{Value: endpoint})
-	node.AddItem("admin_user", ctypes.ConfigValueStr{Value: user})
-	node.AddItem("admin_password", ctypes.ConfigValueStr{Value: password})
-	node.AddItem("admin_tenant", ctypes.ConfigValueStr{Value: tenant})
-	return plugin.ConfigType{ConfigDataNode: node}
+func (s *CollectorSuite) TestCollectMetricsInactivity() {
+	Convey("Given inactivity metric types and config with custom thresholds", s.T(), func() {
+		cfg := setupCfg(th.Endpoint(), "me", "secret", "admin")
+		cfg["inactivity_thresholds"] = "7,365"
+		m1 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "users", "inactive").
+				AddDynamicElement("threshold_days", "number of days without activity").
+				AddStaticElement("count"),
+			Config: cfg}
+		m2 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "users", "never_active_count"),
+			Config:    cfg}
+
+		Convey("When CollectMetrics() is called", func() {
+			mts, err := New().CollectMetrics([]plugin.Metric{m1, m2})
+
+			Convey("Then no error should be reported", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("and users are counted per threshold", func() {
+				metricNames := map[string]interface{}{}
+				for _, m := range mts {
+					metricNames[m.Namespace.String()] = m.Data
+				}
+				So(len(mts), ShouldEqual, 3)
+				So(metricNames["/intel/openstack/keystone/users/inactive/7/count"], ShouldEqual, 1)
+				So(metricNames["/intel/openstack/keystone/users/inactive/365/count"], ShouldEqual, 1)
+				So(metricNames["/intel/openstack/keystone/users/never_active_count"], ShouldEqual, 2)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsPasswords() {
+	Convey("Given password expiry and lockout metric types", s.T(), func() {
+		cfg := setupCfg(th.Endpoint(), "me", "secret", "admin")
+		m1 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "users", "password_expiring_within", "14", "count"),
+			Config:    cfg}
+		m2 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "users", "password_expired_count"),
+			Config:    cfg}
+		m3 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "users", "disabled_count"),
+			Config:    cfg}
+
+		Convey("When CollectMetrics() is called", func() {
+			mts, err := New().CollectMetrics([]plugin.Metric{m1, m2, m3})
+
+			Convey("Then no error should be reported", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("and expired passwords and disabled users are counted", func() {
+				metricNames := map[string]interface{}{}
+				for _, m := range mts {
+					metricNames[m.Namespace.String()] = m.Data
+				}
+				So(len(mts), ShouldEqual, 3)
+				So(metricNames["/intel/openstack/keystone/users/password_expiring_within/14/count"], ShouldEqual, 0)
+				So(metricNames["/intel/openstack/keystone/users/password_expired_count"], ShouldEqual, 1)
+				So(metricNames["/intel/openstack/keystone/users/disabled_count"], ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsChurn() {
+	Convey("Given churn metric types", s.T(), func() {
+		cfg := setupCfg(th.Endpoint(), "me", "secret", "admin")
+		m1 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "users_added_count"),
+			Config:    cfg}
+		m2 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "services_removed_count"),
+			Config:    cfg}
+		collector := New()
+
+		Convey("When CollectMetrics() is called for the first time", func() {
+			mts, err := collector.CollectMetrics([]plugin.Metric{m1, m2})
+
+			Convey("Then no changes are reported, as there is nothing to compare with", func() {
+				So(err, ShouldBeNil)
+				So(mts, ShouldBeEmpty)
+			})
+
+			Convey("and when CollectMetrics() is called again", func() {
+				mts, err := collector.CollectMetrics([]plugin.Metric{m1, m2})
+
+				Convey("Then changes since previous collection are reported", func() {
+					So(err, ShouldBeNil)
+					So(len(mts), ShouldEqual, 2)
+					for _, m := range mts {
+						So(m.Data, ShouldEqual, 0)
+					}
+				})
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsCatalogSnapshot() {
+	Convey("Given config with catalog snapshot enabled", s.T(), func() {
+		cfg := setupCfg(th.Endpoint(), "me", "secret", "admin")
+		cfg["catalog_snapshot"] = true
+
+		Convey("When GetMetricTypes() is called", func() {
+			mts, err := New().GetMetricTypes(cfg)
+
+			Convey("Then catalog snapshot is listed", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 46)
+				So(mts, ShouldContain, plugin.Metric{
+					Namespace:   plugin.NewNamespace("intel", "openstack", "keystone", "catalog", "snapshot"),
+					Description: "JSON document with services, endpoints and regions of service catalog",
+				})
+			})
+		})
+
+		Convey("When CollectMetrics() is called", func() {
+			m1 := plugin.Metric{
+				Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "catalog", "snapshot"),
+				Config:    cfg}
+			mts, err := New().CollectMetrics([]plugin.Metric{m1})
+
+			Convey("Then catalog is reported as JSON document tagged with its hash", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 1)
+
+				var doc struct {
+					Services  []map[string]interface{} `json:"services"`
+					Endpoints []map[string]interface{} `json:"endpoints"`
+					Regions   []string                 `json:"regions"`
+				}
+				So(json.Unmarshal([]byte(mts[0].Data.(string)), &doc), ShouldBeNil)
+				So(len(doc.Services), ShouldEqual, 4)
+				So(len(doc.Endpoints), ShouldEqual, 4)
+				So(doc.Regions, ShouldResemble, []string{"RegionOne"})
+				So(len(mts[0].Tags["hash"]), ShouldEqual, 64)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestGetMetricTypesWithProbes() {
+	Convey("Given config with endpoint probes enabled", s.T(), func() {
+		cfg := setupCfg(th.Endpoint(), "me", "secret", "admin")
+		cfg["probe_endpoints"] = true
+
+		Convey("When GetMetricTypes() is called", func() {
+			mts, err := New().GetMetricTypes(cfg)
+
+			Convey("Then probe metrics are listed", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 47)
+				metricNames := []string{}
+				for _, m := range mts {
+					metricNames = append(metricNames, m.Namespace.String())
+				}
+				So(str.Contains(metricNames, "/intel/openstack/keystone/endpoints/*/*/*/up"), ShouldBeTrue)
+				So(str.Contains(metricNames, "/intel/openstack/keystone/endpoints/*/*/*/latency_ms"), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsConcurrently() {
+	Convey("Given collector shared by several tasks", s.T(), func() {
+		cfg := setupCfg(th.Endpoint(), "me", "secret", "admin")
+		m1 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "users_added_count"),
+			Config:    cfg}
+		m2 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "total_tenants_count"),
+			Config:    cfg}
+		collector := New()
+
+		Convey("When CollectMetrics() is called at the same time", func() {
+			var done sync.WaitGroup
+			errs := make(chan error, 8)
+			for i := 0; i < 8; i++ {
+				done.Add(1)
+				go func() {
+					defer done.Done()
+					_, err := collector.CollectMetrics([]plugin.Metric{m1, m2})
+					errs <- err
+				}()
+			}
+			done.Wait()
+			close(errs)
+
+			Convey("Then every collection succeeds", func() {
+				for err := range errs {
+					So(err, ShouldBeNil)
+				}
+				So(len(collector.previous["users"]), ShouldBeGreaterThan, 0)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsClouds() {
+	Convey("Given collector shared by tasks collecting from different clouds", s.T(), func() {
+		cfg := setupCfg(th.Endpoint(), "me", "secret", "admin")
+		other := setupCfg("http://127.0.0.1:1/", "me", "secret", "admin")
+		m1 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "total_tenants_count"),
+			Config:    cfg}
+		m2 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "total_tenants_count"),
+			Config:    other}
+		collector := New()
+
+		Convey("When CollectMetrics() is called for every cloud", func() {
+			_, err1 := collector.CollectMetrics([]plugin.Metric{m1})
+			mts, err2 := collector.CollectMetrics([]plugin.Metric{m2})
+
+			Convey("Then metrics of the first cloud are not reported for the other one", func() {
+				So(err1, ShouldBeNil)
+				So(err2, ShouldNotBeNil)
+				So(mts, ShouldBeEmpty)
+			})
+
+			Convey("and the first cloud is collected again", func() {
+				mts, err := collector.CollectMetrics([]plugin.Metric{m1})
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 1)
+				So(mts[0].Tags["endpoint"], ShouldEqual, th.Endpoint())
+			})
+		})
+
+		Convey("When the other cloud keeps failing until its circuit breaker opens", func() {
+			for i := 0; i < 6; i++ {
+				collector.CollectMetrics([]plugin.Metric{m2})
+			}
+			_, errOther := collector.CollectMetrics([]plugin.Metric{m2})
+			mts, err := collector.CollectMetrics([]plugin.Metric{m1})
+
+			Convey("Then requests to the first cloud are not suspended", func() {
+				So(errOther, ShouldNotBeNil)
+				So(errOther.Error(), ShouldContainSubstring, "circuit breaker is open")
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When password of the same user changes", func() {
+			_, err := collector.CollectMetrics([]plugin.Metric{m1})
+			So(err, ShouldBeNil)
+			first := collector.provider
+
+			cfg["admin_password"] = "changed"
+			_, err = collector.CollectMetrics([]plugin.Metric{m1})
+
+			Convey("Then collector authenticates again", func() {
+				So(err, ShouldBeNil)
+				So(collector.provider, ShouldNotPointTo, first)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsFiltered() {
+	Convey("Given users count metric type and config excluding tenant", s.T(), func() {
+		cfg := setupCfg(th.Endpoint(), "me", "secret", "admin")
+		cfg["exclude_tenants"] = "/^de/"
+		m1 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone").
+				AddDynamicElement("tenant_name", "name of tenant").
+				AddStaticElement("users_count"),
+			Config: cfg}
+
+		Convey("When CollectMetrics() is called", func() {
+			mts, err := New().CollectMetrics([]plugin.Metric{m1})
+
+			Convey("Then users count of excluded tenant is not returned", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 1)
+				So(mts[0].Namespace.String(), ShouldEqual, "/intel/openstack/keystone/admin/users_count")
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsTags() {
+	Convey("Given set of metric types and config with cloud name", s.T(), func() {
+		cfg := setupCfg(th.Endpoint(), "me", "secret", "admin")
+		cfg["cloud_name"] = "lab"
+		m1 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "demo", "users_count"),
+			Config:    cfg}
+		m2 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "total_endpoints_count"),
+			Config:    cfg}
+		m3 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "total_services_count"),
+			Config:    cfg}
+
+		Convey("When CollectMetrics() is called", func() {
+			collector := New()
+
+			mts, err := collector.CollectMetrics([]plugin.Metric{m1, m2, m3})
+			So(err, ShouldBeNil)
+			So(len(mts), ShouldEqual, 3)
+
+			tags := map[string]map[string]string{}
+			for _, m := range mts {
+				tags[m.Namespace.String()] = m.Tags
+			}
+
+			Convey("Then every metric is tagged with endpoint and cloud name", func() {
+				for _, t := range tags {
+					So(t["endpoint"], ShouldEqual, th.Endpoint())
+					So(t["cloud"], ShouldEqual, "lab")
+				}
+			})
+
+			Convey("and per-tenant metric is tagged with tenant ID", func() {
+				t := tags["/intel/openstack/keystone/demo/users_count"]
+				So(t["tenant_id"], ShouldEqual, "11111")
+				So(t, ShouldNotContainKey, "domain_id")
+			})
+
+			Convey("and catalog metrics are tagged with regions, interfaces and service types", func() {
+				t := tags["/intel/openstack/keystone/total_endpoints_count"]
+				So(t["region"], ShouldEqual, "RegionOne")
+				So(t["interface"], ShouldEqual, "admin,internal,public")
+				So(t["service_type"], ShouldEqual, "cloudformation,computev3,metering,orchestration")
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsPartially() {
+	Convey("Given set of metric types including tenant which does not exist", s.T(), func() {
+		cfg := setupCfg(th.Endpoint(), "me", "secret", "admin")
+		m1 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "ghost", "users_count"),
+			Config:    cfg}
+		m2 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "total_services_count"),
+			Config:    cfg}
+
+		Convey("When CollectMetrics() is called", func() {
+			collector := New()
+
+			mts, err := collector.CollectMetrics([]plugin.Metric{m1, m2})
+
+			Convey("Then metrics which could be computed are returned", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 1)
+				So(mts[0].Namespace.String(), ShouldEqual, "/intel/openstack/keystone/total_services_count")
+				So(mts[0].Data, ShouldEqual, 4)
+			})
+		})
+
+		Convey("When none of requested metrics can be computed", func() {
+			collector := New()
+
+			mts, err := collector.CollectMetrics([]plugin.Metric{m1})
+
+			Convey("Then aggregated error is returned", func() {
+				So(mts, ShouldBeEmpty)
+				So(err, ShouldHaveSameTypeAs, &CollectionError{})
+				So(err.Error(), ShouldContainSubstring, "tenant ghost not found")
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsRateLimited() {
+	Convey("Given config with rate limit defined", s.T(), func() {
+		cfg := setupCfg(th.Endpoint(), "me", "secret", "admin")
+		cfg["max_requests_per_second"] = float64(20)
+		cfg["burst"] = int64(1)
+		m1 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "total_tenants_count"),
+			Config:    cfg}
+		m2 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "rate_limit_wait_ms"),
+			Config:    cfg}
+
+		Convey("When CollectMetrics() is called", func() {
+			collector := New()
+
+			mts, err := collector.CollectMetrics([]plugin.Metric{m1, m2})
+
+			Convey("Then no error should be reported", func() {
+				So(err, ShouldBeNil)
+			})
+
+			Convey("and time spent waiting for rate limiter is returned", func() {
+				So(len(mts), ShouldEqual, 2)
+				for _, m := range mts {
+					if m.Namespace.String() == "/intel/openstack/keystone/rate_limit_wait_ms" {
+						So(m.Data, ShouldBeGreaterThan, 0)
+					}
+				}
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsTimeout() {
+	Convey("Given config with collection timeout which is too short", s.T(), func() {
+		cfg := setupCfg(th.Endpoint(), "me", "secret", "admin")
+		cfg["collection_timeout"] = "1ns"
+		m1 := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "total_tenants_count"),
+			Config:    cfg}
+
+		Convey("When CollectMetrics() is called", func() {
+			collector := New()
+
+			_, err := collector.CollectMetrics([]plugin.Metric{m1})
+
+			Convey("Then collection is cancelled with timeout error", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "collection_timeout")
+			})
+		})
+	})
+}
+
+func setupCfg(endpoint, user, password, tenant string) plugin.Config {
+	return plugin.Config{
+		"admin_endpoint": endpoint,
+		"admin_user":     user,
+		"admin_password": password,
+		"admin_tenant":   tenant,
+	}
 }
 
 func registerRoot() {
@@ -238,12 +761,14 @@ func registerUsers(s *CollectorSuite) {
 						"email": "heat@localhost",
 						"enabled": true,
 						"id": "27b6b98022314a6b9c4524efaedf4694",
+						"last_active_at": "2016-01-01",
 						"name": "heat",
+						"password_expires_at": "2016-02-01T00:00:00.000000",
 						"username": "heat"
 					},
 					{
 						"email": "heat-cfn@localhost",
-						"enabled": true,
+						"enabled": false,
 						"id": "60251a9059f84770acbd037468f2e414",
 						"name": "heat-cfn",
 						"username": "heat-cfn"
@@ -401,6 +926,7 @@ func registerDemoUsers(s *CollectorSuite) {
 						"email": "heat@localhost",
 						"enabled": true,
 						"id": "27b6b98022314a6b9c4524efaedf4694",
+						"last_active_at": "2016-01-01",
 						"name": "heat",
 						"username": "heat"
 					},
@@ -439,6 +965,7 @@ func registerAdminUsers(s *CollectorSuite) {
 						"email": "heat@localhost",
 						"enabled": true,
 						"id": "27b6b98022314a6b9c4524efaedf4694",
+						"last_active_at": "2016-01-01",
 						"name": "heat",
 						"username": "heat"
 					}
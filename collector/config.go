@@ -0,0 +1,551 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+const (
+	defaultRequestTimeout      = 10 * time.Second
+	defaultCollectionTimeout   = 60 * time.Second
+	defaultMaxConcurrency      = 1
+	defaultMaxRequests         = 0
+	defaultBurst               = 1
+	defaultMaxRetries          = 3
+	defaultRetryBaseDelay      = 200 * time.Millisecond
+	defaultRetryMaxDelay       = 5 * time.Second
+	defaultBreakerThreshold    = 5
+	defaultBreakerResetTimeout = 30 * time.Second
+	defaultProbeTimeout        = 5 * time.Second
+
+	defaultInactivityThresholds = "30,90,180"
+	defaultPasswordThresholds   = "7,14,30"
+	defaultCredentialThresholds = "7,30"
+	defaultLatencyPercentiles   = "50,90,99"
+)
+
+// settings holds plugin configuration read from global or metric config
+type settings struct {
+	endpoint string
+	user     string
+	password string
+	tenant   string
+	tenantID string
+
+	cloudName string
+
+	domainName string
+	domainID   string
+
+	tenantFilter *nameFilter
+	domainFilter *nameFilter
+
+	projectTagPrefix string
+
+	stateDir      string
+	auditLogPath  string
+	accessLogPath string
+
+	catalogSnapshot bool
+
+	probeEndpoints          bool
+	probeInterfaces         []string
+	probeRegions            []string
+	probeTimeout            time.Duration
+	probeInsecureSkipVerify bool
+	probeCACertPath         string
+	probeRootCAs            *x509.CertPool
+
+	inactivityThresholds []int
+	passwordThresholds   []int
+	credentialThresholds []int
+
+	latencyPercentiles []int
+
+	insecureSkipVerify bool
+	caCertPath         string
+	rootCAs            *x509.CertPool
+
+	requestTimeout    time.Duration
+	collectionTimeout time.Duration
+	maxConcurrency    int
+
+	maxRequests float64
+	burst       int
+
+	maxRetries     int
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+
+	breakerThreshold    int
+	breakerResetTimeout time.Duration
+}
+
+// newSettings reads and validates plugin configuration
+func newSettings(cfg plugin.Config) (*settings, error) {
+	s := &settings{}
+	for _, item := range []struct {
+		key string
+		dst *string
+	}{
+		{"admin_endpoint", &s.endpoint},
+		{"admin_user", &s.user},
+		{"admin_password", &s.password},
+		{"admin_tenant", &s.tenant},
+	} {
+		value, err := cfg.GetString(item.key)
+		if err != nil {
+			return nil, fmt.Errorf("config item %s: %v", item.key, err)
+		}
+		*item.dst = value
+	}
+
+	var err error
+
+	s.tenantID = getString(cfg, "tenant_id", "")
+	s.cloudName = getString(cfg, "cloud_name", "")
+	s.domainName = getString(cfg, "domain_name", "")
+	s.domainID = getString(cfg, "domain_id", "")
+	s.caCertPath = getString(cfg, "ca_cert_path", "")
+	s.projectTagPrefix = getString(cfg, "project_tag_prefix", "")
+	s.stateDir = getString(cfg, "state_dir", "")
+	s.auditLogPath = getString(cfg, "audit_log_path", "")
+	s.accessLogPath = getString(cfg, "access_log_path", "")
+	s.probeInterfaces = splitList(getString(cfg, "probe_interfaces", ""))
+	s.probeRegions = splitList(getString(cfg, "probe_regions", ""))
+	s.probeCACertPath = getString(cfg, "probe_ca_cert_path", "")
+
+	if s.tenantFilter, err = newNameFilter(getString(cfg, "include_tenants", ""), getString(cfg, "exclude_tenants", "")); err != nil {
+		return nil, err
+	}
+	if s.domainFilter, err = newNameFilter(getString(cfg, "include_domains", ""), getString(cfg, "exclude_domains", "")); err != nil {
+		return nil, err
+	}
+
+	if s.inactivityThresholds, err = parseThresholds(cfg, "inactivity_thresholds", defaultInactivityThresholds); err != nil {
+		return nil, err
+	}
+	if s.passwordThresholds, err = parseThresholds(cfg, "password_expiry_thresholds", defaultPasswordThresholds); err != nil {
+		return nil, err
+	}
+	if s.credentialThresholds, err = parseThresholds(cfg, "credential_expiry_thresholds", defaultCredentialThresholds); err != nil {
+		return nil, err
+	}
+	if s.latencyPercentiles, err = parsePercentiles(cfg, "latency_percentiles", defaultLatencyPercentiles); err != nil {
+		return nil, err
+	}
+
+	if s.insecureSkipVerify, err = getBool(cfg, "insecure_skip_verify", false); err != nil {
+		return nil, err
+	}
+	if s.catalogSnapshot, err = getBool(cfg, "catalog_snapshot", false); err != nil {
+		return nil, err
+	}
+	if s.probeEndpoints, err = getBool(cfg, "probe_endpoints", false); err != nil {
+		return nil, err
+	}
+	if s.probeInsecureSkipVerify, err = getBool(cfg, "probe_insecure_skip_verify", false); err != nil {
+		return nil, err
+	}
+	if s.probeTimeout, err = getDuration(cfg, "probe_timeout", defaultProbeTimeout); err != nil {
+		return nil, err
+	}
+	if s.requestTimeout, err = getDuration(cfg, "request_timeout", defaultRequestTimeout); err != nil {
+		return nil, err
+	}
+	if s.collectionTimeout, err = getDuration(cfg, "collection_timeout", defaultCollectionTimeout); err != nil {
+		return nil, err
+	}
+	if s.maxConcurrency, err = getInt(cfg, "max_concurrency", defaultMaxConcurrency); err != nil {
+		return nil, err
+	}
+	if s.maxRequests, err = getFloat(cfg, "max_requests_per_second", defaultMaxRequests); err != nil {
+		return nil, err
+	}
+	if s.burst, err = getInt(cfg, "burst", defaultBurst); err != nil {
+		return nil, err
+	}
+	if s.maxRetries, err = getInt(cfg, "max_retries", defaultMaxRetries); err != nil {
+		return nil, err
+	}
+	if s.retryBaseDelay, err = getDuration(cfg, "retry_base_delay", defaultRetryBaseDelay); err != nil {
+		return nil, err
+	}
+	if s.retryMaxDelay, err = getDuration(cfg, "retry_max_delay", defaultRetryMaxDelay); err != nil {
+		return nil, err
+	}
+	if s.breakerThreshold, err = getInt(cfg, "breaker_failure_threshold", defaultBreakerThreshold); err != nil {
+		return nil, err
+	}
+	if s.breakerResetTimeout, err = getDuration(cfg, "breaker_reset_timeout", defaultBreakerResetTimeout); err != nil {
+		return nil, err
+	}
+
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+
+	if s.caCertPath != "" {
+		if s.rootCAs, err = loadCertPool("ca_cert_path", s.caCertPath); err != nil {
+			return nil, err
+		}
+	}
+	if s.probeCACertPath != "" {
+		if s.probeRootCAs, err = loadCertPool("probe_ca_cert_path", s.probeCACertPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// validate checks values of config items and their combinations
+func (s *settings) validate() error {
+	u, err := url.Parse(s.endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("admin_endpoint must be http or https URL, got %q", s.endpoint)
+	}
+	if s.domainName != "" && s.domainID != "" {
+		return errors.New("domain_name and domain_id cannot be set at the same time")
+	}
+	if s.insecureSkipVerify && s.caCertPath != "" {
+		return errors.New("ca_cert_path cannot be used when insecure_skip_verify is enabled")
+	}
+	if s.probeInsecureSkipVerify && s.probeCACertPath != "" {
+		return errors.New("probe_ca_cert_path cannot be used when probe_insecure_skip_verify is enabled")
+	}
+
+	for key, value := range map[string]time.Duration{
+		"request_timeout":       s.requestTimeout,
+		"collection_timeout":    s.collectionTimeout,
+		"retry_base_delay":      s.retryBaseDelay,
+		"retry_max_delay":       s.retryMaxDelay,
+		"breaker_reset_timeout": s.breakerResetTimeout,
+		"probe_timeout":         s.probeTimeout,
+	} {
+		if value < 0 {
+			return fmt.Errorf("config item %s cannot be negative", key)
+		}
+	}
+	if s.collectionTimeout > 0 && s.requestTimeout > s.collectionTimeout {
+		return errors.New("request_timeout cannot be longer than collection_timeout")
+	}
+	if s.maxRetries > 0 && s.retryBaseDelay > s.retryMaxDelay {
+		return errors.New("retry_base_delay cannot be longer than retry_max_delay")
+	}
+
+	if s.maxConcurrency < 1 {
+		return errors.New("max_concurrency must be at least 1")
+	}
+	if s.maxRequests < 0 {
+		return errors.New("max_requests_per_second cannot be negative")
+	}
+	if s.burst < 1 {
+		return errors.New("burst must be at least 1")
+	}
+	if s.maxRetries < 0 {
+		return errors.New("max_retries cannot be negative")
+	}
+	if s.breakerThreshold < 0 {
+		return errors.New("breaker_failure_threshold cannot be negative")
+	}
+
+	return nil
+}
+
+// tlsConfig returns TLS configuration of connections to Keystone, or nil when defaults should be used
+func (s *settings) tlsConfig() *tls.Config {
+	if !s.insecureSkipVerify && s.rootCAs == nil {
+		return nil
+	}
+
+	return &tls.Config{
+		InsecureSkipVerify: s.insecureSkipVerify,
+		RootCAs:            s.rootCAs,
+	}
+}
+
+// probeTLSConfig returns TLS configuration of endpoint probes, or nil when defaults should be used
+func (s *settings) probeTLSConfig() *tls.Config {
+	if !s.probeInsecureSkipVerify && s.probeRootCAs == nil {
+		return nil
+	}
+
+	return &tls.Config{
+		InsecureSkipVerify: s.probeInsecureSkipVerify,
+		RootCAs:            s.probeRootCAs,
+	}
+}
+
+// splitList returns non-empty items of comma separated list
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseThresholds parses config item with comma separated list of positive numbers of days,
+// returned sorted without duplicates
+func parseThresholds(cfg plugin.Config, name, defaultValue string) ([]int, error) {
+	value := getString(cfg, name, defaultValue)
+	thresholds, ok := parseNumbers(value, 0)
+	if !ok {
+		return nil, fmt.Errorf("%s must be comma separated positive numbers of days, got %q", name, value)
+	}
+	return thresholds, nil
+}
+
+// parsePercentiles parses config item with comma separated list of percentiles from 1 to 100,
+// returned sorted without duplicates
+func parsePercentiles(cfg plugin.Config, name, defaultValue string) ([]int, error) {
+	value := getString(cfg, name, defaultValue)
+	percentiles, ok := parseNumbers(value, 100)
+	if !ok {
+		return nil, fmt.Errorf("%s must be comma separated numbers from 1 to 100, got %q", name, value)
+	}
+	return percentiles, nil
+}
+
+// parseNumbers parses comma separated list of positive numbers not greater than max (0 - no limit),
+// returned sorted without duplicates
+func parseNumbers(value string, max int) ([]int, bool) {
+	seen := map[int]bool{}
+	numbers := []int{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		number, err := strconv.Atoi(item)
+		if err != nil || number <= 0 || (max > 0 && number > max) {
+			return nil, false
+		}
+		if !seen[number] {
+			seen[number] = true
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Ints(numbers)
+	return numbers, true
+}
+
+// loadCertPool reads PEM encoded CA certificates from file set in config item with given name
+func loadCertPool(name, path string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %v", name, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no PEM encoded certificates found in %s", path)
+	}
+
+	return pool, nil
+}
+
+// configPolicy returns rules of all config items understood by the plugin
+func configPolicy() (*plugin.ConfigPolicy, error) {
+	policy := plugin.NewConfigPolicy()
+	ns := []string{vendor, fs, name}
+
+	for _, key := range []string{"admin_endpoint", "admin_user", "admin_password", "admin_tenant"} {
+		if err := policy.AddNewStringRule(ns, key, true); err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range []string{"tenant_id", "cloud_name", "domain_name", "domain_id", "ca_cert_path",
+		"include_tenants", "exclude_tenants", "include_domains", "exclude_domains", "project_tag_prefix",
+		"state_dir", "audit_log_path", "access_log_path", "probe_interfaces", "probe_regions", "probe_ca_cert_path"} {
+		if err := policy.AddNewStringRule(ns, key, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := policy.AddNewStringRule(ns, "inactivity_thresholds", false,
+		plugin.SetDefaultString(defaultInactivityThresholds)); err != nil {
+		return nil, err
+	}
+	if err := policy.AddNewStringRule(ns, "password_expiry_thresholds", false,
+		plugin.SetDefaultString(defaultPasswordThresholds)); err != nil {
+		return nil, err
+	}
+	if err := policy.AddNewStringRule(ns, "credential_expiry_thresholds", false,
+		plugin.SetDefaultString(defaultCredentialThresholds)); err != nil {
+		return nil, err
+	}
+	if err := policy.AddNewStringRule(ns, "latency_percentiles", false,
+		plugin.SetDefaultString(defaultLatencyPercentiles)); err != nil {
+		return nil, err
+	}
+
+	durations := []struct {
+		key string
+		def time.Duration
+	}{
+		{"request_timeout", defaultRequestTimeout},
+		{"collection_timeout", defaultCollectionTimeout},
+		{"retry_base_delay", defaultRetryBaseDelay},
+		{"retry_max_delay", defaultRetryMaxDelay},
+		{"breaker_reset_timeout", defaultBreakerResetTimeout},
+		{"probe_timeout", defaultProbeTimeout},
+	}
+	for _, d := range durations {
+		if err := policy.AddNewStringRule(ns, d.key, false, plugin.SetDefaultString(d.def.String())); err != nil {
+			return nil, err
+		}
+	}
+
+	integers := []struct {
+		key      string
+		def, min int64
+	}{
+		{"max_concurrency", defaultMaxConcurrency, 1},
+		{"burst", defaultBurst, 1},
+		{"max_retries", defaultMaxRetries, 0},
+		{"breaker_failure_threshold", defaultBreakerThreshold, 0},
+	}
+	for _, i := range integers {
+		if err := policy.AddNewIntRule(ns, i.key, false, plugin.SetDefaultInt(i.def), plugin.SetMinInt(i.min)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := policy.AddNewFloatRule(ns, "max_requests_per_second", false,
+		plugin.SetDefaultFloat(defaultMaxRequests), plugin.SetMinFloat(0)); err != nil {
+		return nil, err
+	}
+	if err := policy.AddNewBoolRule(ns, "insecure_skip_verify", false, plugin.SetDefaultBool(false)); err != nil {
+		return nil, err
+	}
+	if err := policy.AddNewBoolRule(ns, "catalog_snapshot", false, plugin.SetDefaultBool(false)); err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"probe_endpoints", "probe_insecure_skip_verify"} {
+		if err := policy.AddNewBoolRule(ns, key, false, plugin.SetDefaultBool(false)); err != nil {
+			return nil, err
+		}
+	}
+
+	return policy, nil
+}
+
+// getString returns value of optional string config item, or def when item is not set
+func getString(cfg plugin.Config, name, def string) string {
+	item, ok := cfg[name]
+	if !ok {
+		return def
+	}
+
+	value, ok := item.(string)
+	if !ok {
+		return def
+	}
+
+	return value
+}
+
+// getInt returns value of optional integer config item, or def when item is not set
+func getInt(cfg plugin.Config, name string, def int) (int, error) {
+	item, ok := cfg[name]
+	if !ok {
+		return def, nil
+	}
+
+	switch value := item.(type) {
+	case int:
+		return value, nil
+	case int64:
+		return int(value), nil
+	case float64:
+		if value != float64(int(value)) {
+			return 0, fmt.Errorf("config item %s must be an integer, got %v", name, value)
+		}
+		return int(value), nil
+	}
+
+	return 0, fmt.Errorf("config item %s must be an integer, got %T", name, item)
+}
+
+// getFloat returns value of optional numeric config item, or def when item is not set
+func getFloat(cfg plugin.Config, name string, def float64) (float64, error) {
+	item, ok := cfg[name]
+	if !ok {
+		return def, nil
+	}
+
+	switch value := item.(type) {
+	case int:
+		return float64(value), nil
+	case int64:
+		return float64(value), nil
+	case float64:
+		return value, nil
+	}
+
+	return 0, fmt.Errorf("config item %s must be a number, got %T", name, item)
+}
+
+// getDuration returns value of optional duration config item (e.g. "500ms", "10s"), or def when item is not set
+func getDuration(cfg plugin.Config, name string, def time.Duration) (time.Duration, error) {
+	item, ok := cfg[name]
+	if !ok {
+		return def, nil
+	}
+
+	value, ok := item.(string)
+	if !ok {
+		return 0, fmt.Errorf("config item %s must be a duration string, got %T", name, item)
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("config item %s is not a valid duration: %v", name, err)
+	}
+
+	return duration, nil
+}
+
+// getBool returns value of optional boolean config item, or def when item is not set
+func getBool(cfg plugin.Config, name string, def bool) (bool, error) {
+	item, ok := cfg[name]
+	if !ok {
+		return def, nil
+	}
+
+	value, ok := item.(bool)
+	if !ok {
+		return false, fmt.Errorf("config item %s must be a boolean, got %T", name, item)
+	}
+
+	return value, nil
+}
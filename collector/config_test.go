@@ -0,0 +1,190 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetConfigPolicy(t *testing.T) {
+	Convey("Given keystone collector", t, func() {
+		collector := New()
+
+		Convey("When GetConfigPolicy() is called", func() {
+			_, err := collector.GetConfigPolicy()
+
+			Convey("Then no error should be reported", func() {
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestNewSettings(t *testing.T) {
+	Convey("Given config with credentials only", t, func() {
+		cfg := setupCfg("http://keystone:5000", "me", "secret", "admin")
+
+		Convey("When settings are read", func() {
+			s, err := newSettings(cfg)
+
+			Convey("Then defaults are used for optional items", func() {
+				So(err, ShouldBeNil)
+				So(s.endpoint, ShouldEqual, "http://keystone:5000")
+				So(s.requestTimeout, ShouldEqual, defaultRequestTimeout)
+				So(s.collectionTimeout, ShouldEqual, defaultCollectionTimeout)
+				So(s.maxConcurrency, ShouldEqual, defaultMaxConcurrency)
+				So(s.maxRetries, ShouldEqual, defaultMaxRetries)
+				So(s.inactivityThresholds, ShouldResemble, []int{30, 90, 180})
+				So(s.passwordThresholds, ShouldResemble, []int{7, 14, 30})
+				So(s.latencyPercentiles, ShouldResemble, []int{50, 90, 99})
+				So(s.tlsConfig(), ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given config with numbers decoded from JSON", t, func() {
+		cfg := setupCfg("http://keystone:5000", "me", "secret", "admin")
+		cfg["max_concurrency"] = float64(4)
+		cfg["max_requests_per_second"] = int64(5)
+
+		s, err := newSettings(cfg)
+		So(err, ShouldBeNil)
+		So(s.maxConcurrency, ShouldEqual, 4)
+		So(s.maxRequests, ShouldEqual, 5)
+	})
+
+	Convey("Given config with unordered inactivity thresholds", t, func() {
+		cfg := setupCfg("http://keystone:5000", "me", "secret", "admin")
+		cfg["inactivity_thresholds"] = " 90, 7,90 "
+
+		s, err := newSettings(cfg)
+		So(err, ShouldBeNil)
+		So(s.inactivityThresholds, ShouldResemble, []int{7, 90})
+	})
+
+	Convey("Given config without required item", t, func() {
+		cfg := setupCfg("http://keystone:5000", "me", "secret", "admin")
+		delete(cfg, "admin_password")
+
+		_, err := newSettings(cfg)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "admin_password")
+	})
+
+	Convey("Given config with invalid items", t, func() {
+		for item, value := range map[string]interface{}{
+			"request_timeout":            "ten seconds",
+			"max_concurrency":            int64(0),
+			"burst":                      int64(0),
+			"max_retries":                int64(-1),
+			"insecure_skip_verify":       "yes",
+			"ca_cert_path":               "/nonexistent/ca.pem",
+			"inactivity_thresholds":      "30,-1",
+			"password_expiry_thresholds": "week",
+			"latency_percentiles":        "50,101",
+			"catalog_snapshot":           "yes",
+			"probe_timeout":              "-1s",
+			"probe_ca_cert_path":         "/nonexistent/ca.pem",
+		} {
+			cfg := setupCfg("http://keystone:5000", "me", "secret", "admin")
+			cfg[item] = value
+
+			_, err := newSettings(cfg)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Given config with endpoint which is not URL", t, func() {
+		cfg := setupCfg("keystone:5000", "me", "secret", "admin")
+
+		_, err := newSettings(cfg)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "admin_endpoint")
+	})
+
+	Convey("Given config with both domain_name and domain_id", t, func() {
+		cfg := setupCfg("http://keystone:5000", "me", "secret", "admin")
+		cfg["domain_name"] = "default"
+		cfg["domain_id"] = "default"
+
+		_, err := newSettings(cfg)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "domain_name and domain_id")
+	})
+
+	Convey("Given config with request timeout longer than collection timeout", t, func() {
+		cfg := setupCfg("http://keystone:5000", "me", "secret", "admin")
+		cfg["request_timeout"] = "2m"
+		cfg["collection_timeout"] = "1m"
+
+		_, err := newSettings(cfg)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Given config with insecure_skip_verify and ca_cert_path", t, func() {
+		cfg := setupCfg("https://keystone:5000", "me", "secret", "admin")
+		cfg["insecure_skip_verify"] = true
+		cfg["ca_cert_path"] = "/etc/ssl/ca.pem"
+
+		_, err := newSettings(cfg)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Given config with probe_insecure_skip_verify and probe_ca_cert_path", t, func() {
+		cfg := setupCfg("https://keystone:5000", "me", "secret", "admin")
+		cfg["probe_insecure_skip_verify"] = true
+		cfg["probe_ca_cert_path"] = "/etc/ssl/ca.pem"
+
+		_, err := newSettings(cfg)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "probe_ca_cert_path")
+	})
+
+	Convey("Given config with endpoint probes", t, func() {
+		cfg := setupCfg("https://keystone:5000", "me", "secret", "admin")
+		cfg["probe_endpoints"] = true
+		cfg["probe_interfaces"] = "public, internal"
+		cfg["probe_insecure_skip_verify"] = true
+
+		s, err := newSettings(cfg)
+		So(err, ShouldBeNil)
+		So(s.probeInterfaces, ShouldResemble, []string{"public", "internal"})
+		So(s.probeRegions, ShouldBeEmpty)
+		So(s.probeTimeout, ShouldEqual, defaultProbeTimeout)
+		So(s.tlsConfig(), ShouldBeNil)
+		So(s.probeTLSConfig().InsecureSkipVerify, ShouldBeTrue)
+	})
+
+	Convey("Given config with file which does not contain certificates", t, func() {
+		dir, err := ioutil.TempDir("", "keystone")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "ca.pem")
+		So(ioutil.WriteFile(path, []byte("not a certificate"), 0600), ShouldBeNil)
+
+		cfg := setupCfg("https://keystone:5000", "me", "secret", "admin")
+		cfg["ca_cert_path"] = path
+
+		_, err = newSettings(cfg)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "no PEM encoded certificates")
+	})
+}
@@ -0,0 +1,60 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SourceError describes failure of a single data source during collection
+type SourceError struct {
+	// Source is name of data source, e.g. tenants or users
+	Source string
+	Err    error
+}
+
+// Error implements error interface
+func (e SourceError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Source, e.Err)
+}
+
+// CollectionError aggregates errors of all data sources which failed during single collection.
+// Metrics of sources which did not fail are still collected.
+type CollectionError struct {
+	Errors []SourceError
+}
+
+// Error implements error interface
+func (e *CollectionError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("collection failed for %d source(s): %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// add records error of given source
+func (e *CollectionError) add(source string, err error) {
+	e.Errors = append(e.Errors, SourceError{Source: source, Err: err})
+}
+
+// errOrNil returns e, or nil when no errors were recorded
+func (e *CollectionError) errOrNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,141 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/types"
+)
+
+// nameFilter selects names matching include patterns and not matching exclude patterns
+type nameFilter struct {
+	include []func(string) bool
+	exclude []func(string) bool
+}
+
+// newNameFilter parses comma separated lists of include and exclude patterns.
+// Pattern enclosed in slashes, e.g. "/^ci-[0-9]+$/", is a regular expression, otherwise it is a glob, e.g. "ci-*".
+func newNameFilter(include, exclude string) (*nameFilter, error) {
+	f := &nameFilter{}
+	var err error
+	if f.include, err = parsePatterns(include); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = parsePatterns(exclude); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// splitPatterns splits comma separated patterns. Regular expression is kept whole up to the slash followed by comma
+// or end of list, so that it may contain commas, e.g. "/^ci-[0-9]{1,4}$/".
+func splitPatterns(patterns string) []string {
+	split := []string{}
+	for rest := strings.TrimSpace(patterns); rest != ""; {
+		end := strings.Index(rest, ",")
+		if end < 0 {
+			end = len(rest)
+		}
+		if strings.HasPrefix(rest, "/") {
+			for i := 1; i < len(rest); i++ {
+				if rest[i] != '/' {
+					continue
+				}
+				after := strings.TrimLeft(rest[i+1:], " \t")
+				if after == "" || after[0] == ',' {
+					end = len(rest) - len(after)
+					break
+				}
+			}
+		}
+
+		split = append(split, strings.TrimSpace(rest[:end]))
+		rest = strings.TrimSpace(strings.TrimPrefix(rest[end:], ","))
+	}
+	return split
+}
+
+// parsePatterns returns matchers of comma separated patterns
+func parsePatterns(patterns string) ([]func(string) bool, error) {
+	matchers := []func(string) bool{}
+	for _, pattern := range splitPatterns(patterns) {
+		if pattern == "" {
+			continue
+		}
+
+		if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+			re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %s: %v", pattern, err)
+			}
+			matchers = append(matchers, re.MatchString)
+			continue
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %s: %v", pattern, err)
+		}
+		glob := pattern
+		matchers = append(matchers, func(name string) bool {
+			matched, _ := path.Match(glob, name)
+			return matched
+		})
+	}
+	return matchers, nil
+}
+
+// allows checks if any of given names (e.g. name and ID) is included and none of them is excluded.
+// Filter without include patterns includes everything.
+func (f *nameFilter) allows(names ...string) bool {
+	included := len(f.include) == 0
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if matchAny(f.exclude, name) {
+			return false
+		}
+		if matchAny(f.include, name) {
+			included = true
+		}
+	}
+	return included
+}
+
+// matchAny checks if name matches any of matchers
+func matchAny(matchers []func(string) bool, name string) bool {
+	for _, match := range matchers {
+		if match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectTenants returns tenants allowed by tenant and domain filters.
+// Tenants are matched by name or ID, their domains by domain name or ID.
+func (s *settings) selectTenants(tenants []types.Tenant) []types.Tenant {
+	selected := []types.Tenant{}
+	for _, tenant := range tenants {
+		if s.tenantFilter.allows(tenant.Name, tenant.ID) && s.domainFilter.allows(tenant.DomainName, tenant.DomainID) {
+			selected = append(selected, tenant)
+		}
+	}
+	return selected
+}
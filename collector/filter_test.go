@@ -0,0 +1,102 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/types"
+)
+
+func TestNameFilter(t *testing.T) {
+	Convey("Given filter with glob and regular expression patterns", t, func() {
+		f, err := newNameFilter("ci-*, /^prod-[0-9]+$/", "ci-keep*")
+		So(err, ShouldBeNil)
+
+		Convey("Then names matching include patterns are allowed", func() {
+			So(f.allows("ci-1234"), ShouldBeTrue)
+			So(f.allows("prod-7"), ShouldBeTrue)
+		})
+
+		Convey("and names matching exclude patterns or no include pattern are not allowed", func() {
+			So(f.allows("ci-keep-me"), ShouldBeFalse)
+			So(f.allows("prod-x"), ShouldBeFalse)
+			So(f.allows("admin"), ShouldBeFalse)
+		})
+
+		Convey("and any of alternative names can be included", func() {
+			So(f.allows("admin", "ci-1"), ShouldBeTrue)
+			So(f.allows("ci-1", "ci-keep"), ShouldBeFalse)
+		})
+	})
+
+	Convey("Given filter with regular expressions containing commas", t, func() {
+		f, err := newNameFilter("/^ci-[0-9]{1,4}$/, admin,/^(demo|test)-[a-z]{2,}$/", "")
+		So(err, ShouldBeNil)
+		So(len(f.include), ShouldEqual, 3)
+
+		Convey("Then quantifiers with bounds are kept in the pattern", func() {
+			So(f.allows("ci-1234"), ShouldBeTrue)
+			So(f.allows("ci-12345"), ShouldBeFalse)
+			So(f.allows("admin"), ShouldBeTrue)
+			So(f.allows("test-ab"), ShouldBeTrue)
+			So(f.allows("test-a"), ShouldBeFalse)
+		})
+	})
+
+	Convey("Given filter without patterns", t, func() {
+		f, err := newNameFilter("", "")
+		So(err, ShouldBeNil)
+
+		Convey("Then every name is allowed", func() {
+			So(f.allows("admin"), ShouldBeTrue)
+			So(f.allows(""), ShouldBeTrue)
+		})
+	})
+
+	Convey("Given invalid patterns", t, func() {
+		_, err := newNameFilter("/[/", "")
+		So(err, ShouldNotBeNil)
+
+		_, err = newNameFilter("", "ci-[")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestSelectTenants(t *testing.T) {
+	Convey("Given tenants of different domains", t, func() {
+		tenants := []types.Tenant{
+			{Name: "admin", ID: "1", DomainName: "Default", DomainID: "default"},
+			{Name: "ci-1", ID: "2", DomainName: "CI", DomainID: "d-ci"},
+			{Name: "demo", ID: "3", DomainName: "Default", DomainID: "default"},
+		}
+
+		Convey("When tenant and domain filters are set", func() {
+			cfg := setupCfg("http://keystone:5000", "me", "secret", "admin")
+			cfg["exclude_tenants"] = "demo"
+			cfg["include_domains"] = "default"
+			s, err := newSettings(cfg)
+			So(err, ShouldBeNil)
+
+			Convey("Then only tenants allowed by both filters are selected", func() {
+				selected := s.selectTenants(tenants)
+				So(len(selected), ShouldEqual, 1)
+				So(selected[0].Name, ShouldEqual, "admin")
+			})
+		})
+	})
+}
@@ -0,0 +1,264 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	openstackintel "github.com/intelsdi-x/snap-plugin-collector-keystone/openstack"
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/types"
+)
+
+// data sources of collected metrics
+const (
+	srcAuthentication = "authentication"
+	srcTenants        = "tenants"
+	srcUsers          = "users"
+	srcServices       = "services"
+	srcEndpoints      = "endpoints"
+	srcDomains        = "domains"
+	srcCredentials    = "credentials"
+	srcTrusts         = "trusts"
+	srcRevocations    = "revocation_events"
+	srcPolicies       = "policies"
+	srcRegLimits      = "registered_limits"
+	srcLimits         = "limits"
+	srcTenantUsers    = "tenant_users"
+	srcAppCredentials = "application_credentials"
+	srcProbes         = "endpoint_probes"
+
+	// srcAuditLog and srcAccessLog are local logs, read without Keystone requests
+	srcAuditLog  = "audit_log"
+	srcAccessLog = "access_log"
+
+	// srcFreshCatalog makes services and endpoints fetched again instead of taken from previous collection
+	srcFreshCatalog = "fresh_catalog"
+)
+
+// localSources are read from files on the host, so they do not need Keystone to be available
+var localSources = map[string]bool{srcAuditLog: true, srcAccessLog: true}
+
+// errSourceFailed is returned for metric whose data source failed, the failure itself is reported once per source
+var errSourceFailed = errors.New("data source failed")
+
+// inventory holds data gathered from Keystone during single collection
+type inventory struct {
+	tenants     []types.Tenant
+	selected    []types.Tenant
+	users       []types.User
+	services    []types.Service
+	endpoints   []types.Endpoint
+	domains     []types.Domain
+	credentials []types.Credential
+	trusts      []types.Trust
+	revocations []types.RevocationEvent
+	policies    []types.Policy
+	regLimits   []types.RegisteredLimit
+	limits      []types.Limit
+	tenantUsers map[string]int // keyed by tenant ID
+
+	appCredentials []types.ApplicationCredential
+
+	probes []probeResult
+
+	audit    *auditEvents
+	requests *accessRequests
+
+	mutex sync.Mutex
+	errs  map[string]error
+}
+
+// fail records error of given data source
+func (inv *inventory) fail(source string, err error) {
+	inv.mutex.Lock()
+	defer inv.mutex.Unlock()
+
+	inv.errs[source] = err
+}
+
+// available checks if data of given source was gathered successfully
+func (inv *inventory) available(source string) bool {
+	if _, failed := inv.errs[srcAuthentication]; failed && !localSources[source] {
+		return false
+	}
+	if source == srcTenantUsers && !inv.available(srcTenants) {
+		return false
+	}
+	if source == srcAppCredentials && !inv.available(srcUsers) {
+		return false
+	}
+	if source == srcProbes && !(inv.available(srcServices) && inv.available(srcEndpoints)) {
+		return false
+	}
+
+	_, failed := inv.errs[source]
+	return !failed
+}
+
+// collectionError aggregates errors of all failed data sources
+func (inv *inventory) collectionError() *CollectionError {
+	sources := []string{}
+	for source := range inv.errs {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	errs := &CollectionError{}
+	for _, source := range sources {
+		errs.add(source, inv.errs[source])
+	}
+	return errs
+}
+
+// fetch gathers data from sources which are needed by requested metrics.
+// Sources are queried concurrently and failure of one of them does not affect the others.
+func (c *collector) fetch(ctx context.Context, s *settings, needed map[string]bool) *inventory {
+	inv := &inventory{errs: map[string]error{}}
+
+	if needed[srcAuditLog] {
+		var err error
+		if inv.audit, err = c.readAuditLog(s); err != nil {
+			inv.fail(srcAuditLog, err)
+		}
+	}
+	if needed[srcAccessLog] {
+		var err error
+		if inv.requests, err = c.readAccessLog(s); err != nil {
+			inv.fail(srcAccessLog, err)
+		}
+	}
+
+	remote := false
+	for source := range needed {
+		if !localSources[source] {
+			remote = true
+		}
+	}
+	if !remote {
+		return inv
+	}
+
+	if err := c.authenticate(ctx, s); err != nil {
+		inv.fail(srcAuthentication, timeoutError(ctx, err))
+		return inv
+	}
+
+	var done sync.WaitGroup
+	run := func(source string, f func() error) {
+		if !needed[source] {
+			return
+		}
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			if err := f(); err != nil {
+				inv.fail(source, timeoutError(ctx, err))
+			}
+		}()
+	}
+
+	// collect services and endpoint only once, unless their changes are tracked
+	if c.endpoints == nil || needed[srcFreshCatalog] {
+		run(srcEndpoints, func() error {
+			endpoints, err := openstackintel.GetAllEndpoints(ctx, c.provider)
+			if err == nil {
+				c.endpoints = endpoints
+			}
+			return err
+		})
+	}
+	if c.services == nil || needed[srcFreshCatalog] {
+		run(srcServices, func() error {
+			services, err := openstackintel.GetAllServices(ctx, c.provider)
+			if err == nil {
+				c.services = services
+			}
+			return err
+		})
+	}
+
+	run(srcTenants, func() (err error) {
+		inv.tenants, err = openstackintel.GetAllTenants(ctx, c.provider)
+		return err
+	})
+	run(srcUsers, func() (err error) {
+		inv.users, err = openstackintel.GetAllUsers(ctx, c.provider)
+		return err
+	})
+	run(srcDomains, func() (err error) {
+		inv.domains, err = openstackintel.GetAllDomains(ctx, c.provider)
+		return err
+	})
+	run(srcCredentials, func() (err error) {
+		inv.credentials, err = openstackintel.GetAllCredentials(ctx, c.provider)
+		return err
+	})
+	run(srcTrusts, func() (err error) {
+		inv.trusts, err = openstackintel.GetAllTrusts(ctx, c.provider)
+		return err
+	})
+	run(srcRevocations, func() (err error) {
+		inv.revocations, err = openstackintel.GetAllRevocationEvents(ctx, c.provider)
+		return err
+	})
+	run(srcPolicies, func() (err error) {
+		inv.policies, err = openstackintel.GetAllPolicies(ctx, c.provider)
+		return err
+	})
+	run(srcRegLimits, func() (err error) {
+		inv.regLimits, err = openstackintel.GetAllRegisteredLimits(ctx, c.provider)
+		return err
+	})
+	run(srcLimits, func() (err error) {
+		inv.limits, err = openstackintel.GetAllLimits(ctx, c.provider)
+		return err
+	})
+
+	done.Wait()
+	inv.services = c.services
+	inv.endpoints = c.endpoints
+
+	// tenant and domain filters are applied before any per-tenant request
+	inv.selected = s.selectTenants(inv.tenants)
+
+	if needed[srcTenantUsers] && inv.available(srcTenants) {
+		var err error
+		inv.tenantUsers, err = openstackintel.GetUsersPerTenant(ctx, c.provider, inv.selected, s.maxConcurrency)
+		if err != nil {
+			inv.fail(srcTenantUsers, timeoutError(ctx, err))
+		}
+	}
+
+	if needed[srcAppCredentials] && inv.available(srcUsers) {
+		var err error
+		inv.appCredentials, err = openstackintel.GetApplicationCredentials(ctx, c.provider, inv.users, s.maxConcurrency)
+		if err != nil {
+			inv.fail(srcAppCredentials, timeoutError(ctx, err))
+		}
+	}
+
+	if needed[srcProbes] && inv.available(srcProbes) {
+		var err error
+		inv.probes, err = probeEndpoints(ctx, s, inv.services, inv.endpoints)
+		if err != nil {
+			inv.fail(srcProbes, timeoutError(ctx, err))
+		}
+	}
+
+	return inv
+}
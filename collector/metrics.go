@@ -0,0 +1,1309 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+//go:generate go run ../tools/metricstable/main.go ../README.md
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/types"
+)
+
+// metricDef declares metric gathered by the plugin. Adding a metric to metricDefs is enough
+// for it to be listed, collected and documented in README.
+type metricDef struct {
+	namespace   plugin.Namespace
+	dataType    string
+	unit        string
+	description string
+
+	// sources lists Keystone data needed to compute the metric
+	sources []string
+
+	// partial makes metric computed from data gathered so far, even when some of its sources failed
+	partial bool
+
+	// enabled tells if metric is listed with given configuration, metrics without it are always listed
+	enabled func(s *settings) bool
+
+	// compute returns values of the metric, one per each combination of dynamic elements
+	compute func(e *evaluation) []metricValue
+}
+
+// metricValue is a single value computed for metric definition
+type metricValue struct {
+	// dynamic holds values of dynamic namespace elements, in order
+	dynamic []string
+	data    interface{}
+	tags    map[string]string
+}
+
+// evaluation holds everything metric values are computed from during single collection
+type evaluation struct {
+	collector *collector
+	inventory *inventory
+	settings  *settings
+	waited    time.Duration
+	now       time.Time
+
+	// changes holds entities added and removed since previous collection, by entity name
+	changes map[string]*change
+}
+
+// metricDefs is the registry of all metrics gathered by the plugin
+var metricDefs = []metricDef{
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name).
+			AddDynamicElement("tenant_name", "name of tenant").
+			AddStaticElement("users_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Total number of users for given tenant",
+		sources:     []string{srcTenants, srcTenantUsers},
+		partial:     true,
+		compute:     tenantUsersCount,
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "project_tags").
+			AddDynamicElement("tag", "project tag").
+			AddStaticElement("projects_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of projects with given tag (see project_tag_prefix)",
+		sources:     []string{srcTenants},
+		compute: func(e *evaluation) []metricValue {
+			values := []metricValue{}
+			for _, tagged := range projectsPerTag(e) {
+				values = append(values, metricValue{dynamic: []string{tagged.tag}, data: len(tagged.projects)})
+			}
+			return values
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "project_tags").
+			AddDynamicElement("tag", "project tag").
+			AddStaticElement("users_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Total number of users of projects with given tag",
+		sources:     []string{srcTenants, srcTenantUsers},
+		compute: func(e *evaluation) []metricValue {
+			values := []metricValue{}
+			for _, tagged := range projectsPerTag(e) {
+				users := 0
+				for _, project := range tagged.projects {
+					users += e.inventory.tenantUsers[project.ID]
+				}
+				values = append(values, metricValue{dynamic: []string{tagged.tag}, data: users})
+			}
+			return values
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "total_tenants_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Total number of tenants",
+		sources:     []string{srcTenants},
+		compute: func(e *evaluation) []metricValue {
+			return []metricValue{{data: len(e.inventory.tenants)}}
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "total_users_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Total number of users",
+		sources:     []string{srcUsers},
+		compute: func(e *evaluation) []metricValue {
+			return []metricValue{{data: len(e.inventory.users)}}
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "total_endpoints_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Total number of endpoints",
+		sources:     []string{srcEndpoints},
+		compute: func(e *evaluation) []metricValue {
+			return []metricValue{{data: len(e.inventory.endpoints), tags: catalogTags(e.inventory)}}
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "total_services_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Total number of services",
+		sources:     []string{srcServices},
+		compute: func(e *evaluation) []metricValue {
+			return []metricValue{{data: len(e.inventory.services), tags: catalogTags(e.inventory)}}
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "endpoints").
+			AddDynamicElement("service_type", "type of service").
+			AddDynamicElement("region", "region of endpoint").
+			AddDynamicElement("interface", "interface of endpoint: public, internal or admin").
+			AddStaticElement("up"),
+		dataType:    "int",
+		description: "Reachability of catalog endpoint: 1 - responded with status other than 5xx, 0 - otherwise",
+		sources:     []string{srcServices, srcEndpoints, srcFreshCatalog, srcProbes},
+		enabled:     probeEnabled,
+		compute:     probesUp,
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "endpoints").
+			AddDynamicElement("service_type", "type of service").
+			AddDynamicElement("region", "region of endpoint").
+			AddDynamicElement("interface", "interface of endpoint: public, internal or admin").
+			AddStaticElement("latency_ms"),
+		dataType:    "float64",
+		unit:        "ms",
+		description: "Time in milliseconds catalog endpoint took to respond to probe",
+		sources:     []string{srcServices, srcEndpoints, srcFreshCatalog, srcProbes},
+		enabled:     probeEnabled,
+		compute:     probesLatency,
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "catalog", "snapshot"),
+		dataType:    "string",
+		description: "JSON document with services, endpoints and regions of service catalog",
+		sources:     []string{srcServices, srcEndpoints, srcFreshCatalog},
+		enabled:     catalogSnapshotEnabled,
+		compute:     catalogSnapshot,
+	},
+	churnCount("tenants", true),
+	churnCount("tenants", false),
+	churnCount("users", true),
+	churnCount("users", false),
+	churnCount("services", true),
+	churnCount("services", false),
+	churnCount("endpoints", true),
+	churnCount("endpoints", false),
+	churnCount("revocation_events", true),
+	churnCount("revocation_events", false),
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "changes").
+			AddDynamicElement("entity", "type of entity: tenants, users, services or endpoints").
+			AddStaticElement("event"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of entities of given type added or removed since previous collection, their IDs are listed in tags",
+		sources:     []string{srcTenants, srcUsers, srcServices, srcEndpoints, srcFreshCatalog},
+		partial:     true,
+		compute:     churnEvent,
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "users", "inactive").
+			AddDynamicElement("threshold_days", "number of days without activity").
+			AddStaticElement("count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of users whose last activity is older than given number of days (see inactivity_thresholds)",
+		sources:     []string{srcUsers},
+		compute: func(e *evaluation) []metricValue {
+			inactive, _ := countInactive(e.inventory.users, e.settings.inactivityThresholds, e.now)
+			values := []metricValue{}
+			for i, days := range e.settings.inactivityThresholds {
+				values = append(values, metricValue{dynamic: []string{strconv.Itoa(days)}, data: inactive[i]})
+			}
+			return values
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "users", "never_active_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of users who have never logged in",
+		sources:     []string{srcUsers},
+		compute: func(e *evaluation) []metricValue {
+			_, never := countInactive(e.inventory.users, nil, e.now)
+			return []metricValue{{data: never}}
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "users", "password_expiring_within").
+			AddDynamicElement("threshold_days", "number of days until password expires").
+			AddStaticElement("count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of users whose password expires within given number of days (see password_expiry_thresholds)",
+		sources:     []string{srcUsers},
+		compute: func(e *evaluation) []metricValue {
+			expiring, _ := countPasswordExpiry(e.inventory.users, e.settings.passwordThresholds, e.now)
+			values := []metricValue{}
+			for i, days := range e.settings.passwordThresholds {
+				values = append(values, metricValue{dynamic: []string{strconv.Itoa(days)}, data: expiring[i]})
+			}
+			return values
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "users", "password_expired_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of users whose password has expired",
+		sources:     []string{srcUsers},
+		compute: func(e *evaluation) []metricValue {
+			_, expired := countPasswordExpiry(e.inventory.users, nil, e.now)
+			return []metricValue{{data: expired}}
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "users", "disabled_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of disabled users",
+		sources:     []string{srcUsers},
+		compute: func(e *evaluation) []metricValue {
+			disabled := 0
+			for _, user := range e.inventory.users {
+				if !user.Enabled {
+					disabled++
+				}
+			}
+			return []metricValue{{data: disabled}}
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "domains").
+			AddDynamicElement("domain_name", "name of domain").
+			AddStaticElements("users", "inactive").
+			AddDynamicElement("threshold_days", "number of days without activity").
+			AddStaticElement("count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of users of given domain whose last activity is older than given number of days",
+		sources:     []string{srcUsers, srcDomains},
+		compute: func(e *evaluation) []metricValue {
+			values := []metricValue{}
+			for _, domain := range usersPerDomain(e) {
+				inactive, _ := countInactive(domain.users, e.settings.inactivityThresholds, e.now)
+				for i, days := range e.settings.inactivityThresholds {
+					values = append(values, metricValue{
+						dynamic: []string{domain.name, strconv.Itoa(days)},
+						data:    inactive[i],
+						tags:    map[string]string{"domain_id": domain.id},
+					})
+				}
+			}
+			return values
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "domains").
+			AddDynamicElement("domain_name", "name of domain").
+			AddStaticElements("users", "never_active_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of users of given domain who have never logged in",
+		sources:     []string{srcUsers, srcDomains},
+		compute: func(e *evaluation) []metricValue {
+			values := []metricValue{}
+			for _, domain := range usersPerDomain(e) {
+				_, never := countInactive(domain.users, nil, e.now)
+				values = append(values, metricValue{
+					dynamic: []string{domain.name},
+					data:    never,
+					tags:    map[string]string{"domain_id": domain.id},
+				})
+			}
+			return values
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "credentials", "types").
+			AddDynamicElement("credential_type", "type of credential, e.g. ec2, cert or totp").
+			AddStaticElement("count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of credentials of given type",
+		sources:     []string{srcCredentials},
+		compute: func(e *evaluation) []metricValue {
+			counts := map[string]int{}
+			for _, credential := range e.inventory.credentials {
+				counts[credential.Type]++
+			}
+			values := []metricValue{}
+			for _, credentialType := range sortedKeys(counts) {
+				values = append(values, metricValue{dynamic: []string{credentialType}, data: counts[credentialType]})
+			}
+			return values
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "credentials", "types").
+			AddDynamicElement("credential_type", "type of credential, e.g. ec2, cert or totp").
+			AddStaticElement("projects").
+			AddDynamicElement("project_name", "name of project").
+			AddStaticElement("count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of credentials of given type scoped to given project",
+		sources:     []string{srcCredentials, srcTenants},
+		compute:     credentialsPerProject,
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "credentials", "application", "count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of application credentials",
+		sources:     []string{srcUsers, srcAppCredentials},
+		compute: func(e *evaluation) []metricValue {
+			return []metricValue{{data: len(e.inventory.appCredentials)}}
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "credentials", "application", "users").
+			AddDynamicElement("user_name", "name of user").
+			AddStaticElement("count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of application credentials of given user",
+		sources:     []string{srcUsers, srcAppCredentials},
+		compute: func(e *evaluation) []metricValue {
+			counts := map[string]int{}
+			for _, credential := range e.inventory.appCredentials {
+				counts[credential.UserID]++
+			}
+			values := []metricValue{}
+			for _, user := range e.inventory.users {
+				if counts[user.ID] == 0 {
+					continue
+				}
+				values = append(values, metricValue{
+					dynamic: []string{user.Name},
+					data:    counts[user.ID],
+					tags:    map[string]string{"user_id": user.ID},
+				})
+			}
+			return values
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "credentials", "application", "expiring_within").
+			AddDynamicElement("threshold_days", "number of days until application credential expires").
+			AddStaticElement("count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of application credentials which expire within given number of days (see credential_expiry_thresholds)",
+		sources:     []string{srcUsers, srcAppCredentials},
+		compute: func(e *evaluation) []metricValue {
+			expiring, _, _ := countCredentialExpiry(e.inventory.appCredentials, e.settings.credentialThresholds, e.now)
+			values := []metricValue{}
+			for i, days := range e.settings.credentialThresholds {
+				values = append(values, metricValue{dynamic: []string{strconv.Itoa(days)}, data: expiring[i]})
+			}
+			return values
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "credentials", "application", "expired_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of application credentials which have expired",
+		sources:     []string{srcUsers, srcAppCredentials},
+		compute: func(e *evaluation) []metricValue {
+			_, expired, _ := countCredentialExpiry(e.inventory.appCredentials, nil, e.now)
+			return []metricValue{{data: expired}}
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "credentials", "application", "no_expiry_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of application credentials which never expire",
+		sources:     []string{srcUsers, srcAppCredentials},
+		compute: func(e *evaluation) []metricValue {
+			_, _, never := countCredentialExpiry(e.inventory.appCredentials, nil, e.now)
+			return []metricValue{{data: never}}
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "total_trusts_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Total number of trusts",
+		sources:     []string{srcTrusts},
+		compute: func(e *evaluation) []metricValue {
+			return []metricValue{{data: len(e.inventory.trusts)}}
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "trusts_expired_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of trusts which have expired",
+		sources:     []string{srcTrusts},
+		compute: func(e *evaluation) []metricValue {
+			expired := 0
+			for _, trust := range e.inventory.trusts {
+				if trust.ExpiresAt != nil && !trust.ExpiresAt.After(e.now) {
+					expired++
+				}
+			}
+			return []metricValue{{data: expired}}
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "trusts_without_expiry_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of trusts which never expire",
+		sources:     []string{srcTrusts},
+		compute: func(e *evaluation) []metricValue {
+			never := 0
+			for _, trust := range e.inventory.trusts {
+				if trust.ExpiresAt == nil {
+					never++
+				}
+			}
+			return []metricValue{{data: never}}
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "trusts_with_impersonation_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of trusts which allow trustee to impersonate trustor",
+		sources:     []string{srcTrusts},
+		compute: func(e *evaluation) []metricValue {
+			impersonating := 0
+			for _, trust := range e.inventory.trusts {
+				if trust.Impersonation {
+					impersonating++
+				}
+			}
+			return []metricValue{{data: impersonating}}
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "total_revocation_events_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Total number of token revocation events",
+		sources:     []string{srcRevocations},
+		compute: func(e *evaluation) []metricValue {
+			return []metricValue{{data: len(e.inventory.revocations)}}
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "oldest_revocation_event_age_s"),
+		dataType:    "int",
+		unit:        "s",
+		description: "Age in seconds of the oldest token revocation event",
+		sources:     []string{srcRevocations},
+		compute:     oldestRevocationAge,
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "total_policies_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Total number of policies",
+		sources:     []string{srcPolicies},
+		compute: func(e *evaluation) []metricValue {
+			return []metricValue{{data: len(e.inventory.policies)}}
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "limits", "services").
+			AddDynamicElement("service_name", "name of service").
+			AddStaticElement("registered_limits_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of registered (default) limits of resources of given service",
+		sources:     []string{srcServices, srcRegLimits},
+		compute: func(e *evaluation) []metricValue {
+			counts := map[string]int{}
+			for _, limit := range e.inventory.regLimits {
+				counts[limit.ServiceID]++
+			}
+			return perService(e.inventory, counts)
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "limits", "services").
+			AddDynamicElement("service_name", "name of service").
+			AddStaticElement("limits_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of limits of resources of given service set for projects or domains",
+		sources:     []string{srcServices, srcLimits},
+		compute: func(e *evaluation) []metricValue {
+			counts := map[string]int{}
+			for _, limit := range e.inventory.limits {
+				counts[limit.ServiceID]++
+			}
+			return perService(e.inventory, counts)
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "limits", "services").
+			AddDynamicElement("service_name", "name of service").
+			AddStaticElement("override_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of limits of resources of given service which differ from registered default",
+		sources:     []string{srcServices, srcRegLimits, srcLimits},
+		compute: func(e *evaluation) []metricValue {
+			defaults := registeredDefaults(e.inventory)
+			counts := map[string]int{}
+			for _, limit := range e.inventory.limits {
+				// services whose limits keep defaults are reported with zero
+				count := counts[limit.ServiceID]
+				if overrides(limit, defaults) {
+					count++
+				}
+				counts[limit.ServiceID] = count
+			}
+			return perService(e.inventory, counts)
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "limits", "projects").
+			AddDynamicElement("project_name", "name of project").
+			AddStaticElement("services").
+			AddDynamicElement("service_name", "name of service").
+			AddStaticElement("resources").
+			AddDynamicElement("resource_name", "name of limited resource").
+			AddStaticElement("limit"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Limit of resource of given service set for given project",
+		sources:     []string{srcServices, srcTenants, srcRegLimits, srcLimits},
+		compute:     projectLimits,
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "audit", "events_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of CADF events read from audit log since previous collection",
+		sources:     []string{srcAuditLog},
+		enabled:     auditEnabled,
+		compute: func(e *evaluation) []metricValue {
+			return []metricValue{{data: e.inventory.audit.total}}
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "audit", "parse_errors_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of audit log lines which could not be parsed since previous collection",
+		sources:     []string{srcAuditLog},
+		enabled:     auditEnabled,
+		compute: func(e *evaluation) []metricValue {
+			return []metricValue{{data: e.inventory.audit.parseErrors}}
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "audit", "authenticate").
+			AddDynamicElement("initiator_domain", "ID of initiator domain").
+			AddStaticElement("success_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of successful authentications in given domain since previous collection",
+		sources:     []string{srcAuditLog},
+		enabled:     auditEnabled,
+		compute: func(e *evaluation) []metricValue {
+			return auditCounts(e, "authenticate", "success", false)
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "audit", "authenticate").
+			AddDynamicElement("initiator_domain", "ID of initiator domain").
+			AddStaticElement("failure_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of failed authentications in given domain since previous collection",
+		sources:     []string{srcAuditLog},
+		enabled:     auditEnabled,
+		compute: func(e *evaluation) []metricValue {
+			return auditCounts(e, "authenticate", "failure", false)
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "audit", "resources").
+			AddDynamicElement("resource_type", "type of resource, e.g. user, project or role").
+			AddDynamicElement("initiator_domain", "ID of initiator domain").
+			AddStaticElement("created_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of resources of given type created by initiators of given domain since previous collection",
+		sources:     []string{srcAuditLog},
+		enabled:     auditEnabled,
+		compute: func(e *evaluation) []metricValue {
+			return auditCounts(e, "created", "", true)
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "audit", "resources").
+			AddDynamicElement("resource_type", "type of resource, e.g. user, project or role").
+			AddDynamicElement("initiator_domain", "ID of initiator domain").
+			AddStaticElement("deleted_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of resources of given type deleted by initiators of given domain since previous collection",
+		sources:     []string{srcAuditLog},
+		enabled:     auditEnabled,
+		compute: func(e *evaluation) []metricValue {
+			return auditCounts(e, "deleted", "", true)
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "access_log", "requests_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of requests read from access log since previous collection",
+		sources:     []string{srcAccessLog},
+		enabled:     accessLogEnabled,
+		compute: func(e *evaluation) []metricValue {
+			return []metricValue{{data: e.inventory.requests.total}}
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "access_log", "parse_errors_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of access log lines which could not be parsed since previous collection",
+		sources:     []string{srcAccessLog},
+		enabled:     accessLogEnabled,
+		compute: func(e *evaluation) []metricValue {
+			return []metricValue{{data: e.inventory.requests.parseErrors}}
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "access_log", "routes").
+			AddDynamicElement("method", "HTTP method").
+			AddDynamicElement("route", "route template, e.g. v3_users_ID_projects").
+			AddStaticElement("requests_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of requests of given route since previous collection",
+		sources:     []string{srcAccessLog},
+		enabled:     accessLogEnabled,
+		compute: func(e *evaluation) []metricValue {
+			return routeValues(e, func(r *routeRequests) interface{} { return r.total })
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "access_log", "routes").
+			AddDynamicElement("method", "HTTP method").
+			AddDynamicElement("route", "route template, e.g. v3_users_ID_projects").
+			AddStaticElement("status").
+			AddDynamicElement("status_class", "class of response status, e.g. 2xx or 5xx").
+			AddStaticElement("requests_count"),
+		dataType:    "int",
+		unit:        "count",
+		description: "Number of requests of given route answered with status of given class since previous collection",
+		sources:     []string{srcAccessLog},
+		enabled:     accessLogEnabled,
+		compute:     routeStatuses,
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "access_log", "routes").
+			AddDynamicElement("method", "HTTP method").
+			AddDynamicElement("route", "route template, e.g. v3_users_ID_projects").
+			AddStaticElement("error_ratio"),
+		dataType:    "float64",
+		description: "Ratio of requests of given route answered with 5xx status since previous collection",
+		sources:     []string{srcAccessLog},
+		enabled:     accessLogEnabled,
+		compute: func(e *evaluation) []metricValue {
+			return routeValues(e, func(r *routeRequests) interface{} {
+				return float64(r.statuses["5xx"]) / float64(r.total)
+			})
+		},
+	},
+	{
+		namespace: plugin.NewNamespace(vendor, fs, name, "access_log", "routes").
+			AddDynamicElement("method", "HTTP method").
+			AddDynamicElement("route", "route template, e.g. v3_users_ID_projects").
+			AddStaticElement("latency").
+			AddDynamicElement("percentile", "percentile set in latency_percentiles").
+			AddStaticElement("ms"),
+		dataType:    "float64",
+		unit:        "ms",
+		description: "Percentile of response time of given route since previous collection",
+		sources:     []string{srcAccessLog},
+		enabled:     accessLogEnabled,
+		compute:     routeLatencies,
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "rate_limit_wait_ms"),
+		dataType:    "float64",
+		unit:        "ms",
+		description: "Time in milliseconds requests spent waiting for rate limiter since previous collection",
+		compute: func(e *evaluation) []metricValue {
+			return []metricValue{{data: float64(e.waited) / float64(time.Millisecond)}}
+		},
+	},
+	{
+		namespace:   plugin.NewNamespace(vendor, fs, name, "circuit_breaker_state"),
+		dataType:    "int",
+		description: "State of circuit breaker guarding Keystone requests: 0 - closed, 1 - half-open, 2 - open",
+		compute: func(e *evaluation) []metricValue {
+			return []metricValue{{data: int(e.collector.breakerState())}}
+		},
+	},
+}
+
+// tenantUsersCount returns users count of every selected tenant which could be queried, in the order Keystone lists tenants
+func tenantUsersCount(e *evaluation) []metricValue {
+	values := []metricValue{}
+	for _, tenant := range e.inventory.selected {
+		count, ok := e.inventory.tenantUsers[tenant.ID]
+		if !ok {
+			continue
+		}
+
+		tags := map[string]string{}
+		addTag(tags, "tenant_id", tenant.ID)
+		addTag(tags, "domain_id", tenant.DomainID)
+		addTag(tags, "domain_name", tenant.DomainName)
+		addTag(tags, "parent_id", tenant.ParentID)
+
+		values = append(values, metricValue{dynamic: []string{tenant.Name}, data: count, tags: tags})
+	}
+	return values
+}
+
+// taggedProjects groups projects with a single tag
+type taggedProjects struct {
+	tag      string
+	projects []types.Tenant
+}
+
+// projectsPerTag groups projects allowed by tenant and domain filters by their tags starting with project_tag_prefix
+func projectsPerTag(e *evaluation) []*taggedProjects {
+	byTag := map[string]*taggedProjects{}
+	tags := []string{}
+	for _, project := range e.inventory.selected {
+		for _, tag := range project.Tags {
+			if !strings.HasPrefix(tag, e.settings.projectTagPrefix) {
+				continue
+			}
+			tagged, ok := byTag[tag]
+			if !ok {
+				tagged = &taggedProjects{tag: tag}
+				byTag[tag] = tagged
+				tags = append(tags, tag)
+			}
+			tagged.projects = append(tagged.projects, project)
+		}
+	}
+	sort.Strings(tags)
+
+	grouped := []*taggedProjects{}
+	for _, tag := range tags {
+		grouped = append(grouped, byTag[tag])
+	}
+	return grouped
+}
+
+// oldestRevocationAge returns age of the oldest revocation event, taken from the time it was revoked or,
+// when Keystone does not report it, from the time before which revoked tokens were issued.
+// Nothing is reported when there are no revocation events.
+func oldestRevocationAge(e *evaluation) []metricValue {
+	var oldest *time.Time
+	for i := range e.inventory.revocations {
+		event := &e.inventory.revocations[i]
+		revoked := &event.IssuedBefore
+		if event.RevokedAt != nil {
+			revoked = event.RevokedAt
+		}
+		if oldest == nil || revoked.Before(*oldest) {
+			oldest = revoked
+		}
+	}
+	if oldest == nil {
+		return []metricValue{}
+	}
+	return []metricValue{{data: int(e.now.Sub(*oldest) / time.Second)}}
+}
+
+// auditEnabled tells if audit log is configured
+func auditEnabled(s *settings) bool {
+	return s.auditLogPath != ""
+}
+
+// countInactive returns numbers of users whose last activity is older than each of thresholds (in days)
+// and number of users who have never been active. Users who have never been active are not counted as inactive.
+func countInactive(users []types.User, thresholds []int, now time.Time) ([]int, int) {
+	inactive := make([]int, len(thresholds))
+	never := 0
+	for _, user := range users {
+		if user.LastActiveAt == nil {
+			never++
+			continue
+		}
+		for i, days := range thresholds {
+			if now.Sub(*user.LastActiveAt) > time.Duration(days)*24*time.Hour {
+				inactive[i]++
+			}
+		}
+	}
+	return inactive, never
+}
+
+// countPasswordExpiry returns numbers of users whose password expires within each of thresholds (in days)
+// and number of users whose password has already expired. Expired passwords are not counted as expiring.
+func countPasswordExpiry(users []types.User, thresholds []int, now time.Time) ([]int, int) {
+	expiring := make([]int, len(thresholds))
+	expired := 0
+	for _, user := range users {
+		if user.PasswordExpiresAt == nil {
+			continue
+		}
+		left := user.PasswordExpiresAt.Sub(now)
+		if left <= 0 {
+			expired++
+			continue
+		}
+		for i, days := range thresholds {
+			if left <= time.Duration(days)*24*time.Hour {
+				expiring[i]++
+			}
+		}
+	}
+	return expiring, expired
+}
+
+// credentialsPerProject counts credentials per type and project. Only projects allowed by tenant and domain filters
+// are reported, credentials which are not scoped to a project are counted in totals per type only.
+func credentialsPerProject(e *evaluation) []metricValue {
+	counts := map[string]map[string]int{}
+	for _, credential := range e.inventory.credentials {
+		if credential.ProjectID == "" {
+			continue
+		}
+		if counts[credential.Type] == nil {
+			counts[credential.Type] = map[string]int{}
+		}
+		counts[credential.Type][credential.ProjectID]++
+	}
+
+	credentialTypes := []string{}
+	for credentialType := range counts {
+		credentialTypes = append(credentialTypes, credentialType)
+	}
+	sort.Strings(credentialTypes)
+
+	values := []metricValue{}
+	for _, credentialType := range credentialTypes {
+		for _, tenant := range e.inventory.selected {
+			count, ok := counts[credentialType][tenant.ID]
+			if !ok {
+				continue
+			}
+			values = append(values, metricValue{
+				dynamic: []string{credentialType, tenant.Name},
+				data:    count,
+				tags:    map[string]string{"project_id": tenant.ID},
+			})
+		}
+	}
+	return values
+}
+
+// limitKey identifies limited resource of a service in a region
+type limitKey struct {
+	serviceID, regionID, resourceName string
+}
+
+// registeredDefaults returns registered (default) limits by resource
+func registeredDefaults(inv *inventory) map[limitKey]int {
+	defaults := map[limitKey]int{}
+	for _, limit := range inv.regLimits {
+		defaults[limitKey{limit.ServiceID, limit.RegionID, limit.ResourceName}] = limit.DefaultLimit
+	}
+	return defaults
+}
+
+// overrides checks if limit differs from registered default of its resource
+func overrides(limit types.Limit, defaults map[limitKey]int) bool {
+	defaultLimit, ok := defaults[limitKey{limit.ServiceID, limit.RegionID, limit.ResourceName}]
+	return ok && defaultLimit != limit.ResourceLimit
+}
+
+// serviceName returns name of service with given ID, or the ID when service is not in catalog
+func serviceName(inv *inventory, id string) (string, string) {
+	for _, service := range inv.services {
+		if service.ID == id {
+			return service.Name, service.Type
+		}
+	}
+	return id, ""
+}
+
+// perService returns values counted per service ID, with service name as dynamic element
+func perService(inv *inventory, counts map[string]int) []metricValue {
+	values := []metricValue{}
+	for _, id := range sortedKeys(counts) {
+		name, serviceType := serviceName(inv, id)
+		tags := map[string]string{}
+		addTag(tags, "service_id", id)
+		addTag(tags, "service_type", serviceType)
+		values = append(values, metricValue{dynamic: []string{name}, data: counts[id], tags: tags})
+	}
+	return values
+}
+
+// projectLimits returns limits set for projects allowed by tenant and domain filters, together with
+// registered defaults they override. Limits set for domains are counted per service only.
+func projectLimits(e *evaluation) []metricValue {
+	projects := map[string]types.Tenant{}
+	for _, tenant := range e.inventory.selected {
+		projects[tenant.ID] = tenant
+	}
+	defaults := registeredDefaults(e.inventory)
+
+	values := []metricValue{}
+	for _, limit := range e.inventory.limits {
+		project, ok := projects[limit.ProjectID]
+		if !ok {
+			continue
+		}
+		service, _ := serviceName(e.inventory, limit.ServiceID)
+
+		tags := map[string]string{}
+		addTag(tags, "project_id", project.ID)
+		addTag(tags, "service_id", limit.ServiceID)
+		addTag(tags, "region_id", limit.RegionID)
+		if defaultLimit, ok := defaults[limitKey{limit.ServiceID, limit.RegionID, limit.ResourceName}]; ok {
+			tags["default_limit"] = strconv.Itoa(defaultLimit)
+		}
+		tags["override"] = strconv.FormatBool(overrides(limit, defaults))
+
+		values = append(values, metricValue{
+			dynamic: []string{project.Name, service, limit.ResourceName},
+			data:    limit.ResourceLimit,
+			tags:    tags,
+		})
+	}
+	return values
+}
+
+// countCredentialExpiry returns numbers of application credentials which expire within each of thresholds (in days),
+// number of expired application credentials and number of application credentials which never expire
+func countCredentialExpiry(credentials []types.ApplicationCredential, thresholds []int, now time.Time) ([]int, int, int) {
+	expiring := make([]int, len(thresholds))
+	expired, never := 0, 0
+	for _, credential := range credentials {
+		if credential.ExpiresAt == nil {
+			never++
+			continue
+		}
+		left := credential.ExpiresAt.Sub(now)
+		if left <= 0 {
+			expired++
+			continue
+		}
+		for i, days := range thresholds {
+			if left <= time.Duration(days)*24*time.Hour {
+				expiring[i]++
+			}
+		}
+	}
+	return expiring, expired, never
+}
+
+// domainUsers groups users of a single domain
+type domainUsers struct {
+	id    string
+	name  string
+	users []types.User
+}
+
+// usersPerDomain groups users by domain allowed by domain filter. Every listed domain is returned, even without users.
+// Domain is identified by its name, or by ID when domain is not listed.
+func usersPerDomain(e *evaluation) []*domainUsers {
+	domains := []*domainUsers{}
+	byID := map[string]*domainUsers{}
+	for _, domain := range e.inventory.domains {
+		d := &domainUsers{id: domain.ID, name: domain.Name}
+		domains = append(domains, d)
+		byID[domain.ID] = d
+	}
+
+	for _, user := range e.inventory.users {
+		if user.DomainID == "" {
+			continue
+		}
+		d, ok := byID[user.DomainID]
+		if !ok {
+			d = &domainUsers{id: user.DomainID, name: user.DomainID}
+			domains = append(domains, d)
+			byID[user.DomainID] = d
+		}
+		d.users = append(d.users, user)
+	}
+
+	allowed := []*domainUsers{}
+	for _, d := range domains {
+		if e.settings.domainFilter.allows(d.name, d.id) {
+			allowed = append(allowed, d)
+		}
+	}
+	return allowed
+}
+
+// catalogTags returns regions, interfaces and types of services found in the catalog
+func catalogTags(inv *inventory) map[string]string {
+	regions, interfaces, serviceTypes := []string{}, []string{}, []string{}
+	for _, endpoint := range inv.endpoints {
+		regions = append(regions, endpoint.Region)
+		interfaces = append(interfaces, endpoint.Availability)
+	}
+	for _, service := range inv.services {
+		serviceTypes = append(serviceTypes, service.Type)
+	}
+
+	tags := map[string]string{}
+	addTag(tags, "region", joinDistinct(regions))
+	addTag(tags, "interface", joinDistinct(interfaces))
+	addTag(tags, "service_type", joinDistinct(serviceTypes))
+	return tags
+}
+
+// snapshot is the document reported by catalog snapshot metric
+type snapshot struct {
+	Services  []types.Service  `json:"services"`
+	Endpoints []types.Endpoint `json:"endpoints"`
+	Regions   []string         `json:"regions"`
+}
+
+// catalogSnapshot returns JSON document of service catalog tagged with its SHA-256 hash. Services and endpoints
+// are sorted by ID, so that the document and its hash change only when the catalog changes.
+func catalogSnapshot(e *evaluation) []metricValue {
+	doc := snapshot{Services: []types.Service{}, Endpoints: []types.Endpoint{}, Regions: []string{}}
+
+	serviceIDs := []string{}
+	services := map[string]types.Service{}
+	for _, service := range e.inventory.services {
+		serviceIDs = append(serviceIDs, service.ID)
+		services[service.ID] = service
+	}
+	sort.Strings(serviceIDs)
+	for _, id := range serviceIDs {
+		doc.Services = append(doc.Services, services[id])
+	}
+
+	endpointIDs := []string{}
+	endpoints := map[string]types.Endpoint{}
+	regions := map[string]bool{}
+	for _, endpoint := range e.inventory.endpoints {
+		endpointIDs = append(endpointIDs, endpoint.ID)
+		endpoints[endpoint.ID] = endpoint
+		if endpoint.Region != "" && !regions[endpoint.Region] {
+			regions[endpoint.Region] = true
+			doc.Regions = append(doc.Regions, endpoint.Region)
+		}
+	}
+	sort.Strings(endpointIDs)
+	for _, id := range endpointIDs {
+		doc.Endpoints = append(doc.Endpoints, endpoints[id])
+	}
+	sort.Strings(doc.Regions)
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return []metricValue{}
+	}
+	hash := sha256.Sum256(data)
+	return []metricValue{{data: string(data), tags: map[string]string{"hash": hex.EncodeToString(hash[:])}}}
+}
+
+// catalogSnapshotEnabled tells if catalog snapshot is requested
+func catalogSnapshotEnabled(s *settings) bool {
+	return s.catalogSnapshot
+}
+
+// findMetric returns definition of metric with given namespace, or nil when there is no such metric.
+// Dynamic elements of the definition match any value.
+func findMetric(ns plugin.Namespace) *metricDef {
+	for i := range metricDefs {
+		if metricDefs[i].matches(ns) {
+			return &metricDefs[i]
+		}
+	}
+	return nil
+}
+
+// matches checks if given namespace refers to this metric
+func (m *metricDef) matches(ns plugin.Namespace) bool {
+	if len(ns) != len(m.namespace) {
+		return false
+	}
+	for i, element := range m.namespace {
+		if element.Name == "" && element.Value != ns[i].Value {
+			return false
+		}
+	}
+	return true
+}
+
+// available checks if all sources of the metric were gathered successfully
+func (m *metricDef) available(inv *inventory) bool {
+	for _, source := range m.sources {
+		if !inv.available(source) {
+			return false
+		}
+	}
+	return true
+}
+
+// values returns values of the metric which match given namespace.
+// It returns errSourceFailed when sources of the metric failed, unless metric can be computed partially,
+// and error when requested dynamic element was not found.
+func (m *metricDef) values(e *evaluation, ns plugin.Namespace) ([]metricValue, error) {
+	available := m.available(e.inventory)
+	if !available && !m.partial {
+		return nil, errSourceFailed
+	}
+
+	dynamic := dynamicElements(m.namespace)
+	matching := []metricValue{}
+	for _, value := range m.compute(e) {
+		if value.matches(ns, dynamic) {
+			matching = append(matching, value)
+		}
+	}
+
+	if len(matching) == 0 {
+		for _, i := range dynamic {
+			if ns[i].Value == "*" {
+				continue
+			}
+			if !available {
+				return nil, errSourceFailed
+			}
+			// e.g. "tenant demo not found" for tenant_name element
+			return nil, fmt.Errorf("%s %s not found", strings.TrimSuffix(m.namespace[i].Name, "_name"), ns[i].Value)
+		}
+	}
+
+	return matching, nil
+}
+
+// matches checks if value belongs to given namespace, whose dynamic elements are at given indexes
+func (v metricValue) matches(ns plugin.Namespace, dynamic []int) bool {
+	for j, i := range dynamic {
+		if ns[i].Value != "*" && ns[i].Value != v.dynamic[j] {
+			return false
+		}
+	}
+	return true
+}
+
+// namespace returns copy of given namespace with dynamic elements set to values of v
+func (v metricValue) namespace(ns plugin.Namespace, dynamic []int) plugin.Namespace {
+	namespace := make(plugin.Namespace, len(ns))
+	copy(namespace, ns)
+	for j, i := range dynamic {
+		namespace[i].Value = v.dynamic[j]
+	}
+	return namespace
+}
+
+// dynamicElements returns indexes of dynamic elements of given namespace
+func dynamicElements(ns plugin.Namespace) []int {
+	indexes := []int{}
+	for i, element := range ns {
+		if element.Name != "" {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// neededSources returns sources needed by metrics with given namespaces
+func neededSources(namespaces []plugin.Namespace) map[string]bool {
+	needed := map[string]bool{}
+	for _, ns := range namespaces {
+		if m := findMetric(ns); m != nil {
+			for _, source := range m.sources {
+				needed[source] = true
+			}
+		}
+	}
+	return needed
+}
+
+// markers surrounding table of metrics in README
+const (
+	MetricsTableStart = "<!-- metrics table start -->"
+	MetricsTableEnd   = "<!-- metrics table end -->"
+)
+
+// MetricsTable returns markdown table describing all metrics gathered by the plugin
+func MetricsTable() string {
+	var buf bytes.Buffer
+	buf.WriteString("Namespace | Data Type | Unit | Description\n")
+	buf.WriteString("----------|-----------|------|------------\n")
+	for _, m := range metricDefs {
+		elements := []string{}
+		for _, element := range m.namespace {
+			if element.Name != "" {
+				elements = append(elements, "\\<"+element.Name+"\\>")
+			} else {
+				elements = append(elements, element.Value)
+			}
+		}
+		fmt.Fprintf(&buf, "%s | %s | %s | %s\n", strings.Join(elements, "/"), m.dataType, m.unit, m.description)
+	}
+	return buf.String()
+}
+
+// addTag sets tag with given key, unless value is empty
+func addTag(tags map[string]string, key, value string) {
+	if value != "" {
+		tags[key] = value
+	}
+}
+
+// joinDistinct returns sorted, comma separated list of distinct non-empty values
+func joinDistinct(values []string) string {
+	distinct := []string{}
+	seen := map[string]bool{}
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			distinct = append(distinct, value)
+		}
+	}
+	sort.Strings(distinct)
+	return strings.Join(distinct, ",")
+}
+
+// sortedKeys returns keys of given map in order
+func sortedKeys(m map[string]int) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
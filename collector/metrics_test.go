@@ -0,0 +1,428 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/types"
+)
+
+func TestMetricDefs(t *testing.T) {
+	Convey("Given registry of metrics", t, func() {
+
+		Convey("Then every metric is fully declared and has unique namespace", func() {
+			namespaces := map[string]bool{}
+			for _, m := range metricDefs {
+				So(m.namespace.Strings()[:3], ShouldResemble, []string{vendor, fs, name})
+				So(m.dataType, ShouldNotBeEmpty)
+				So(m.description, ShouldNotBeEmpty)
+				So(m.compute, ShouldNotBeNil)
+				So(namespaces[m.namespace.String()], ShouldBeFalse)
+				namespaces[m.namespace.String()] = true
+			}
+		})
+
+		Convey("and metric is found by namespace with value of dynamic element", func() {
+			m := findMetric(plugin.NewNamespace(vendor, fs, name, "demo", "users_count"))
+			So(m, ShouldNotBeNil)
+			So(m.sources, ShouldContain, srcTenantUsers)
+
+			So(findMetric(plugin.NewNamespace(vendor, fs, name, "total_users_count")), ShouldNotBeNil)
+			So(findMetric(plugin.NewNamespace(vendor, fs, name, "demo", "admins_count")), ShouldBeNil)
+		})
+
+		Convey("and table of metrics in README is up to date", func() {
+			readme, err := ioutil.ReadFile("../README.md")
+			So(err, ShouldBeNil)
+			So(string(readme), ShouldContainSubstring, MetricsTableStart+"\n"+MetricsTable()+MetricsTableEnd)
+			So(strings.Count(MetricsTable(), "\n"), ShouldEqual, len(metricDefs)+2)
+		})
+	})
+}
+
+func TestInactivity(t *testing.T) {
+	Convey("Given users with different last activity", t, func() {
+		now := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+		daysAgo := func(days int) *time.Time {
+			at := now.Add(-time.Duration(days) * 24 * time.Hour)
+			return &at
+		}
+		e := &evaluation{
+			inventory: &inventory{
+				users: []types.User{
+					{ID: "1", DomainID: "default", LastActiveAt: daysAgo(1)},
+					{ID: "2", DomainID: "default", LastActiveAt: daysAgo(45)},
+					{ID: "3", DomainID: "ldap", LastActiveAt: daysAgo(200)},
+					{ID: "4", DomainID: "ldap"},
+					{ID: "5", DomainID: "unknown"},
+					{ID: "6"},
+				},
+				domains: []types.Domain{{ID: "default", Name: "Default"}, {ID: "ldap", Name: "LDAP"}, {ID: "empty", Name: "Empty"}},
+			},
+			settings: &settings{inactivityThresholds: []int{30, 90, 180}, domainFilter: &nameFilter{}},
+			now:      now,
+		}
+
+		Convey("Then users are counted against every threshold", func() {
+			inactive, never := countInactive(e.inventory.users, e.settings.inactivityThresholds, now)
+			So(inactive, ShouldResemble, []int{2, 1, 1})
+			So(never, ShouldEqual, 3)
+		})
+
+		Convey("and users are grouped by domain", func() {
+			domains := usersPerDomain(e)
+			So(len(domains), ShouldEqual, 4)
+			So(domains[0].name, ShouldEqual, "Default")
+			So(len(domains[0].users), ShouldEqual, 2)
+			So(domains[1].name, ShouldEqual, "LDAP")
+			So(len(domains[1].users), ShouldEqual, 2)
+			So(domains[2].name, ShouldEqual, "Empty")
+			So(domains[2].users, ShouldBeEmpty)
+			So(domains[3].name, ShouldEqual, "unknown")
+		})
+
+		Convey("and domain filter is applied", func() {
+			filter, err := newNameFilter("", "ldap")
+			So(err, ShouldBeNil)
+			e.settings.domainFilter = filter
+			domains := usersPerDomain(e)
+			So(len(domains), ShouldEqual, 3)
+			for _, d := range domains {
+				So(d.id, ShouldNotEqual, "ldap")
+			}
+		})
+	})
+}
+
+func TestPasswordExpiry(t *testing.T) {
+	Convey("Given users with different password expiry", t, func() {
+		now := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+		in := func(days int) *time.Time {
+			at := now.Add(time.Duration(days) * 24 * time.Hour)
+			return &at
+		}
+		users := []types.User{
+			{ID: "1", PasswordExpiresAt: in(3)},
+			{ID: "2", PasswordExpiresAt: in(14)},
+			{ID: "3", PasswordExpiresAt: in(60)},
+			{ID: "4", PasswordExpiresAt: in(-1)},
+			{ID: "5", PasswordExpiresAt: &now},
+			{ID: "6"},
+		}
+
+		Convey("Then expiring passwords are counted against every threshold", func() {
+			expiring, expired := countPasswordExpiry(users, []int{7, 14, 30}, now)
+			So(expiring, ShouldResemble, []int{1, 2, 2})
+			So(expired, ShouldEqual, 2)
+		})
+	})
+}
+
+func TestCredentials(t *testing.T) {
+	Convey("Given credentials and application credentials", t, func() {
+		now := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+		in := func(days int) *time.Time {
+			at := now.Add(time.Duration(days) * 24 * time.Hour)
+			return &at
+		}
+		e := &evaluation{
+			inventory: &inventory{
+				selected: []types.Tenant{{ID: "p1", Name: "demo"}, {ID: "p2", Name: "admin"}},
+				credentials: []types.Credential{
+					{ID: "1", Type: "ec2", ProjectID: "p1"},
+					{ID: "2", Type: "ec2", ProjectID: "p1"},
+					{ID: "3", Type: "ec2", ProjectID: "p2"},
+					{ID: "4", Type: "ec2", ProjectID: "p3"},
+					{ID: "5", Type: "totp"},
+				},
+				users: []types.User{{ID: "u1", Name: "demo"}, {ID: "u2", Name: "admin"}},
+				appCredentials: []types.ApplicationCredential{
+					{ID: "a1", UserID: "u1", ExpiresAt: in(3)},
+					{ID: "a2", UserID: "u1", ExpiresAt: in(20)},
+					{ID: "a3", UserID: "u1", ExpiresAt: in(-5)},
+					{ID: "a4", UserID: "u1"},
+				},
+			},
+			settings: &settings{credentialThresholds: []int{7, 30}},
+			now:      now,
+		}
+
+		Convey("Then credentials are counted per type and selected project", func() {
+			values := credentialsPerProject(e)
+			So(len(values), ShouldEqual, 2)
+			So(values[0].dynamic, ShouldResemble, []string{"ec2", "demo"})
+			So(values[0].data, ShouldEqual, 2)
+			So(values[0].tags["project_id"], ShouldEqual, "p1")
+			So(values[1].dynamic, ShouldResemble, []string{"ec2", "admin"})
+			So(values[1].data, ShouldEqual, 1)
+		})
+
+		Convey("and application credentials are counted per user having any", func() {
+			values, err := findMetric(plugin.NewNamespace(vendor, fs, name, "credentials", "application", "users", "*", "count")).
+				values(e, plugin.NewNamespace(vendor, fs, name, "credentials", "application", "users", "*", "count"))
+			So(err, ShouldBeNil)
+			So(len(values), ShouldEqual, 1)
+			So(values[0].dynamic, ShouldResemble, []string{"demo"})
+			So(values[0].data, ShouldEqual, 4)
+		})
+
+		Convey("and application credentials are counted by expiry", func() {
+			expiring, expired, never := countCredentialExpiry(e.inventory.appCredentials, e.settings.credentialThresholds, now)
+			So(expiring, ShouldResemble, []int{1, 2})
+			So(expired, ShouldEqual, 1)
+			So(never, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestTrusts(t *testing.T) {
+	Convey("Given trusts with different expiry and impersonation", t, func() {
+		now := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+		past := now.Add(-time.Hour)
+		future := now.Add(time.Hour)
+		e := &evaluation{
+			inventory: &inventory{
+				trusts: []types.Trust{
+					{ID: "1", ExpiresAt: &past, Impersonation: true},
+					{ID: "2", ExpiresAt: &now},
+					{ID: "3", ExpiresAt: &future, Impersonation: true},
+					{ID: "4"},
+				},
+			},
+			now: now,
+		}
+		value := func(metric string) interface{} {
+			ns := plugin.NewNamespace(vendor, fs, name, metric)
+			values, err := findMetric(ns).values(e, ns)
+			So(err, ShouldBeNil)
+			So(len(values), ShouldEqual, 1)
+			return values[0].data
+		}
+
+		Convey("Then trusts are counted", func() {
+			So(value("total_trusts_count"), ShouldEqual, 4)
+			So(value("trusts_expired_count"), ShouldEqual, 2)
+			So(value("trusts_without_expiry_count"), ShouldEqual, 1)
+			So(value("trusts_with_impersonation_count"), ShouldEqual, 2)
+		})
+	})
+}
+
+func TestCatalogSnapshot(t *testing.T) {
+	Convey("Given service catalog", t, func() {
+		inv := &inventory{
+			services: []types.Service{{ID: "s2", Name: "nova", Type: "compute"}, {ID: "s1", Name: "keystone", Type: "identity"}},
+			endpoints: []types.Endpoint{
+				{ID: "e2", ServiceID: "s2", URL: "http://nova:8774", Region: "RegionTwo", Availability: "public"},
+				{ID: "e1", ServiceID: "s1", URL: "http://keystone:5000", Region: "RegionOne", Availability: "public"},
+				{ID: "e3", ServiceID: "s1", URL: "http://keystone:35357", Region: "RegionOne", Availability: "admin"},
+			},
+		}
+
+		Convey("When snapshot is computed", func() {
+			values := catalogSnapshot(&evaluation{inventory: inv})
+
+			Convey("Then catalog is reported sorted by IDs", func() {
+				So(len(values), ShouldEqual, 1)
+				So(values[0].data, ShouldStartWith, `{"services":[{"name":"keystone","id":"s1"`)
+				So(values[0].data, ShouldEndWith, `"regions":["RegionOne","RegionTwo"]}`)
+				So(strings.Index(values[0].data.(string), `"id":"e1"`), ShouldBeLessThan,
+					strings.Index(values[0].data.(string), `"id":"e2"`))
+			})
+
+			Convey("and hash does not depend on order of entities", func() {
+				inv.services[0], inv.services[1] = inv.services[1], inv.services[0]
+				inv.endpoints[0], inv.endpoints[2] = inv.endpoints[2], inv.endpoints[0]
+				reordered := catalogSnapshot(&evaluation{inventory: inv})
+				So(reordered[0].tags["hash"], ShouldEqual, values[0].tags["hash"])
+
+				inv.endpoints = inv.endpoints[1:]
+				changed := catalogSnapshot(&evaluation{inventory: inv})
+				So(changed[0].tags["hash"], ShouldNotEqual, values[0].tags["hash"])
+			})
+		})
+	})
+}
+
+func TestRevocations(t *testing.T) {
+	Convey("Given revocation events with and without revocation time", t, func() {
+		now := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+		revoked := now.Add(-time.Hour)
+		e := &evaluation{
+			inventory: &inventory{
+				revocations: []types.RevocationEvent{
+					{UserID: "u1", IssuedBefore: now.Add(-2 * time.Hour), RevokedAt: &revoked},
+					{UserID: "u2", IssuedBefore: now.Add(-90 * time.Minute)},
+				},
+			},
+			now: now,
+		}
+		value := func(metric string) []metricValue {
+			ns := plugin.NewNamespace(vendor, fs, name, metric)
+			values, err := findMetric(ns).values(e, ns)
+			So(err, ShouldBeNil)
+			return values
+		}
+
+		Convey("Then events are counted and age of the oldest one is reported", func() {
+			So(value("total_revocation_events_count")[0].data, ShouldEqual, 2)
+			So(value("oldest_revocation_event_age_s")[0].data, ShouldEqual, 90*60)
+		})
+
+		Convey("and age is not reported without events", func() {
+			e.inventory.revocations = nil
+			So(value("total_revocation_events_count")[0].data, ShouldEqual, 0)
+			So(value("oldest_revocation_event_age_s"), ShouldBeEmpty)
+		})
+	})
+}
+
+func TestLimits(t *testing.T) {
+	Convey("Given registered limits and limits of projects and domains", t, func() {
+		e := &evaluation{
+			inventory: &inventory{
+				selected: []types.Tenant{{ID: "p1", Name: "demo"}, {ID: "p2", Name: "admin"}},
+				services: []types.Service{{ID: "s1", Name: "cinder", Type: "volumev3"}},
+				regLimits: []types.RegisteredLimit{
+					{ServiceID: "s1", RegionID: "RegionOne", ResourceName: "volume", DefaultLimit: 10},
+					{ServiceID: "s1", RegionID: "RegionOne", ResourceName: "snapshot", DefaultLimit: 5},
+					{ServiceID: "s2", RegionID: "RegionOne", ResourceName: "cores", DefaultLimit: 20},
+				},
+				limits: []types.Limit{
+					{ProjectID: "p1", ServiceID: "s1", RegionID: "RegionOne", ResourceName: "volume", ResourceLimit: 50},
+					{ProjectID: "p2", ServiceID: "s1", RegionID: "RegionOne", ResourceName: "volume", ResourceLimit: 10},
+					{ProjectID: "p3", ServiceID: "s1", RegionID: "RegionOne", ResourceName: "snapshot", ResourceLimit: 1},
+					{DomainID: "default", ServiceID: "s2", RegionID: "RegionOne", ResourceName: "cores", ResourceLimit: 20},
+				},
+			},
+		}
+		values := func(ns plugin.Namespace) map[string]metricValue {
+			vs, err := findMetric(ns).values(e, ns)
+			So(err, ShouldBeNil)
+			byName := map[string]metricValue{}
+			for _, v := range vs {
+				byName[strings.Join(v.dynamic, "/")] = v
+			}
+			return byName
+		}
+
+		Convey("Then limits are counted per service", func() {
+			registered := values(plugin.NewNamespace(vendor, fs, name, "limits", "services", "*", "registered_limits_count"))
+			So(registered["cinder"].data, ShouldEqual, 2)
+			So(registered["cinder"].tags["service_type"], ShouldEqual, "volumev3")
+			So(registered["s2"].data, ShouldEqual, 1)
+
+			limits := values(plugin.NewNamespace(vendor, fs, name, "limits", "services", "*", "limits_count"))
+			So(limits["cinder"].data, ShouldEqual, 3)
+			So(limits["s2"].data, ShouldEqual, 1)
+		})
+
+		Convey("and limits which differ from registered defaults are counted as overrides", func() {
+			overrides := values(plugin.NewNamespace(vendor, fs, name, "limits", "services", "*", "override_count"))
+			So(overrides["cinder"].data, ShouldEqual, 2)
+			So(overrides["s2"].data, ShouldEqual, 0)
+		})
+
+		Convey("and limits of selected projects are reported with their defaults", func() {
+			limits := values(plugin.NewNamespace(vendor, fs, name, "limits", "projects", "*", "services", "*", "resources", "*", "limit"))
+			So(len(limits), ShouldEqual, 2)
+			So(limits["demo/cinder/volume"].data, ShouldEqual, 50)
+			So(limits["demo/cinder/volume"].tags, ShouldResemble, map[string]string{
+				"project_id":    "p1",
+				"service_id":    "s1",
+				"region_id":     "RegionOne",
+				"default_limit": "10",
+				"override":      "true",
+			})
+			So(limits["admin/cinder/volume"].tags["override"], ShouldEqual, "false")
+		})
+	})
+}
+
+func TestTenantUsersCount(t *testing.T) {
+	Convey("Given projects with the same name in different domains", t, func() {
+		e := &evaluation{
+			inventory: &inventory{
+				selected: []types.Tenant{
+					{ID: "p1", Name: "ci", DomainID: "d1", Tags: []string{"env:ci"}},
+					{ID: "p2", Name: "ci", DomainID: "d2", Tags: []string{"env:ci"}},
+				},
+				tenantUsers: map[string]int{"p1": 3, "p2": 5},
+			},
+			settings: &settings{},
+		}
+
+		Convey("Then users of every project are counted separately", func() {
+			values := tenantUsersCount(e)
+			So(len(values), ShouldEqual, 2)
+			So(values[0].data, ShouldEqual, 3)
+			So(values[0].tags["tenant_id"], ShouldEqual, "p1")
+			So(values[1].data, ShouldEqual, 5)
+			So(values[1].tags["tenant_id"], ShouldEqual, "p2")
+		})
+
+		Convey("and summed per tag", func() {
+			ns := plugin.NewNamespace(vendor, fs, name, "project_tags", "*", "users_count")
+			values, err := findMetric(ns).values(e, ns)
+			So(err, ShouldBeNil)
+			So(len(values), ShouldEqual, 1)
+			So(values[0].data, ShouldEqual, 8)
+		})
+	})
+}
+
+func TestProjectTags(t *testing.T) {
+	Convey("Given tagged projects", t, func() {
+		e := &evaluation{
+			inventory: &inventory{
+				selected: []types.Tenant{
+					{ID: "p1", Name: "demo", Tags: []string{"env:prod", "cost:42"}},
+					{ID: "p2", Name: "admin", Tags: []string{"env:prod"}},
+					{ID: "p3", Name: "ci"},
+				},
+				tenantUsers: map[string]int{"p1": 3, "p2": 2, "p3": 7},
+			},
+			settings: &settings{},
+		}
+		values := func(metric string) map[string]interface{} {
+			ns := plugin.NewNamespace(vendor, fs, name, "project_tags", "*", metric)
+			vs, err := findMetric(ns).values(e, ns)
+			So(err, ShouldBeNil)
+			byTag := map[string]interface{}{}
+			for _, v := range vs {
+				byTag[v.dynamic[0]] = v.data
+			}
+			return byTag
+		}
+
+		Convey("Then projects and their users are counted per tag", func() {
+			So(values("projects_count"), ShouldResemble, map[string]interface{}{"env:prod": 2, "cost:42": 1})
+			So(values("users_count"), ShouldResemble, map[string]interface{}{"env:prod": 5, "cost:42": 3})
+		})
+
+		Convey("and only tags with configured prefix are kept", func() {
+			e.settings.projectTagPrefix = "env:"
+			So(values("projects_count"), ShouldResemble, map[string]interface{}{"env:prod": 2})
+		})
+	})
+}
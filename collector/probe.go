@@ -0,0 +1,268 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/types"
+)
+
+// unknownRegion is reported for endpoints without region
+const unknownRegion = "unknown"
+
+// probeResult is the outcome of probing single catalog endpoint
+type probeResult struct {
+	endpoint    types.Endpoint
+	serviceName string
+	serviceType string
+
+	// up is set when the endpoint responded with status other than 5xx
+	up bool
+	// latency is the time to response headers, it is known only when the endpoint responded
+	latency   time.Duration
+	responded bool
+}
+
+// probeKey groups probes reported by single metric
+type probeKey struct {
+	serviceType string
+	region      string
+	iface       string
+}
+
+// key returns element values of metrics reporting the probe
+func (p *probeResult) key() probeKey {
+	region := p.endpoint.Region
+	if region == "" {
+		region = unknownRegion
+	}
+	return probeKey{serviceType: p.serviceType, region: region, iface: p.endpoint.Availability}
+}
+
+// probeURL returns URL of endpoint to be probed. Endpoint URLs may contain templates substituted by clients,
+// e.g. "http://nova:8774/v2.1/%(tenant_id)s", such URLs are probed up to the template.
+func probeURL(endpointURL string) string {
+	for _, template := range []string{"%(", "$("} {
+		if i := strings.Index(endpointURL, template); i >= 0 {
+			endpointURL = endpointURL[:i]
+		}
+	}
+	return endpointURL
+}
+
+// selectProbed returns endpoints matching probe_interfaces and probe_regions
+func (s *settings) selectProbed(endpoints []types.Endpoint) []types.Endpoint {
+	selected := []types.Endpoint{}
+	for _, endpoint := range endpoints {
+		if len(s.probeInterfaces) > 0 && !contains(s.probeInterfaces, endpoint.Availability) {
+			continue
+		}
+		if len(s.probeRegions) > 0 && !contains(s.probeRegions, endpoint.Region) {
+			continue
+		}
+		selected = append(selected, endpoint)
+	}
+	return selected
+}
+
+// contains checks if list holds given item
+func contains(list []string, item string) bool {
+	for _, listed := range list {
+		if listed == item {
+			return true
+		}
+	}
+	return false
+}
+
+// probeEndpoints sends GET request to every selected catalog endpoint, at most max_concurrency at the same time.
+// Failed probes are reported as endpoints being down, error is returned only when collection was cancelled.
+func probeEndpoints(ctx context.Context, s *settings, services []types.Service, endpoints []types.Endpoint) ([]probeResult, error) {
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			TLSClientConfig:   s.probeTLSConfig(),
+			DisableKeepAlives: true,
+		},
+		Timeout: s.probeTimeout,
+		// redirect is a response as well, it is not followed
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	servicesByID := map[string]types.Service{}
+	for _, service := range services {
+		servicesByID[service.ID] = service
+	}
+
+	selected := s.selectProbed(endpoints)
+	results := make([]probeResult, len(selected))
+	var done sync.WaitGroup
+	slots := make(chan struct{}, s.maxConcurrency)
+
+	for i, endpoint := range selected {
+		if ctx.Err() != nil {
+			results = results[:i]
+			break
+		}
+
+		service, ok := servicesByID[endpoint.ServiceID]
+		if !ok {
+			service.Type = "unknown"
+		}
+		results[i] = probeResult{endpoint: endpoint, serviceName: service.Name, serviceType: service.Type}
+
+		slots <- struct{}{}
+		done.Add(1)
+		go func(result *probeResult) {
+			defer func() {
+				<-slots
+				done.Done()
+			}()
+			probe(ctx, client, result)
+		}(&results[i])
+	}
+	done.Wait()
+
+	return results, ctx.Err()
+}
+
+// probe sends GET request to the endpoint and records its outcome in result
+func probe(ctx context.Context, client *http.Client, result *probeResult) {
+	req, err := http.NewRequest("GET", probeURL(result.endpoint.URL), nil)
+	if err != nil {
+		return
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return
+	}
+	result.latency = time.Since(start)
+	result.responded = true
+	result.up = resp.StatusCode < http.StatusInternalServerError
+
+	io.CopyN(ioutil.Discard, resp.Body, 4096)
+	resp.Body.Close()
+}
+
+// groupProbes groups probes by service type, region and interface, sorted by them
+func groupProbes(probes []probeResult) ([]probeKey, map[probeKey][]probeResult) {
+	keys := []probeKey{}
+	grouped := map[probeKey][]probeResult{}
+	for _, p := range probes {
+		key := p.key()
+		if _, ok := grouped[key]; !ok {
+			keys = append(keys, key)
+		}
+		grouped[key] = append(grouped[key], p)
+	}
+	sort.Sort(byProbeKey(keys))
+	return keys, grouped
+}
+
+// byProbeKey sorts probe keys by service type, region and interface
+type byProbeKey []probeKey
+
+func (k byProbeKey) Len() int      { return len(k) }
+func (k byProbeKey) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+func (k byProbeKey) Less(i, j int) bool {
+	if k[i].serviceType != k[j].serviceType {
+		return k[i].serviceType < k[j].serviceType
+	}
+	if k[i].region != k[j].region {
+		return k[i].region < k[j].region
+	}
+	return k[i].iface < k[j].iface
+}
+
+// probeTags returns tags describing probed endpoints
+func probeTags(probes []probeResult) map[string]string {
+	ids, names, urls := []string{}, []string{}, []string{}
+	for _, p := range probes {
+		ids = append(ids, p.endpoint.ID)
+		names = append(names, p.serviceName)
+		urls = append(urls, p.endpoint.URL)
+	}
+
+	tags := map[string]string{}
+	addTag(tags, "endpoint_id", joinDistinct(ids))
+	addTag(tags, "service_name", joinDistinct(names))
+	addTag(tags, "url", joinDistinct(urls))
+	return tags
+}
+
+// probesUp returns 1 for every group of endpoints which all responded with status other than 5xx, 0 otherwise
+func probesUp(e *evaluation) []metricValue {
+	values := []metricValue{}
+	keys, grouped := groupProbes(e.inventory.probes)
+	for _, key := range keys {
+		up := 1
+		for _, p := range grouped[key] {
+			if !p.up {
+				up = 0
+			}
+		}
+		values = append(values, metricValue{
+			dynamic: []string{key.serviceType, key.region, key.iface},
+			data:    up,
+			tags:    probeTags(grouped[key]),
+		})
+	}
+	return values
+}
+
+// probesLatency returns the longest response time of every group of endpoints, groups in which no endpoint
+// responded are left out
+func probesLatency(e *evaluation) []metricValue {
+	values := []metricValue{}
+	keys, grouped := groupProbes(e.inventory.probes)
+	for _, key := range keys {
+		var latency time.Duration
+		responded := false
+		for _, p := range grouped[key] {
+			if p.responded {
+				responded = true
+				if p.latency > latency {
+					latency = p.latency
+				}
+			}
+		}
+		if !responded {
+			continue
+		}
+		values = append(values, metricValue{
+			dynamic: []string{key.serviceType, key.region, key.iface},
+			data:    float64(latency) / float64(time.Millisecond),
+			tags:    probeTags(grouped[key]),
+		})
+	}
+	return values
+}
+
+// probeEnabled tells if endpoint probes are enabled
+func probeEnabled(s *settings) bool {
+	return s.probeEndpoints
+}
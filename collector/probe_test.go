@@ -0,0 +1,151 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/types"
+)
+
+func TestProbeURL(t *testing.T) {
+	Convey("Given endpoint URLs", t, func() {
+		Convey("Then templates are left out of probed URL", func() {
+			So(probeURL("http://keystone:5000/v3"), ShouldEqual, "http://keystone:5000/v3")
+			So(probeURL("http://nova:8774/v2.1/%(tenant_id)s"), ShouldEqual, "http://nova:8774/v2.1/")
+			So(probeURL("http://swift:8080/v1/AUTH_$(project_id)s"), ShouldEqual, "http://swift:8080/v1/AUTH_")
+		})
+	})
+}
+
+func TestSelectProbed(t *testing.T) {
+	Convey("Given endpoints in different regions and interfaces", t, func() {
+		endpoints := []types.Endpoint{
+			{ID: "e1", Region: "RegionOne", Availability: "public"},
+			{ID: "e2", Region: "RegionOne", Availability: "admin"},
+			{ID: "e3", Region: "RegionTwo", Availability: "public"},
+		}
+
+		Convey("Then all endpoints are probed without filters", func() {
+			s := &settings{}
+			So(len(s.selectProbed(endpoints)), ShouldEqual, 3)
+		})
+
+		Convey("Then endpoints are filtered by interface and region", func() {
+			s := &settings{probeInterfaces: []string{"public", "internal"}, probeRegions: []string{"RegionOne"}}
+			So(s.selectProbed(endpoints), ShouldResemble, []types.Endpoint{endpoints[0]})
+		})
+	})
+}
+
+func TestProbeEndpoints(t *testing.T) {
+	Convey("Given catalog with endpoints in different state", t, func() {
+		healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer healthy.Close()
+		broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer broken.Close()
+		hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer hung.Close()
+		secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/v3/", http.StatusFound)
+		}))
+		defer secure.Close()
+
+		services := []types.Service{
+			{ID: "s1", Name: "keystone", Type: "identity"},
+			{ID: "s2", Name: "nova", Type: "compute"},
+		}
+		endpoints := []types.Endpoint{
+			{ID: "e1", ServiceID: "s1", URL: healthy.URL + "/v3", Region: "RegionOne", Availability: "public"},
+			{ID: "e2", ServiceID: "s1", URL: secure.URL + "/v3", Region: "RegionOne", Availability: "admin"},
+			{ID: "e3", ServiceID: "s2", URL: broken.URL + "/v2.1/%(tenant_id)s", Region: "RegionOne", Availability: "public"},
+			{ID: "e4", ServiceID: "s2", URL: hung.URL, Availability: "internal"},
+			{ID: "e5", ServiceID: "s2", URL: healthy.URL, Region: "RegionOne", Availability: "public"},
+		}
+		s := &settings{maxConcurrency: 2, probeTimeout: 100 * time.Millisecond}
+
+		value := func(values []metricValue, dynamic ...string) *metricValue {
+			for i := range values {
+				if len(values[i].dynamic) == len(dynamic) && values[i].dynamic[0] == dynamic[0] &&
+					values[i].dynamic[1] == dynamic[1] && values[i].dynamic[2] == dynamic[2] {
+					return &values[i]
+				}
+			}
+			return nil
+		}
+
+		Convey("When endpoints are probed", func() {
+			probes, err := probeEndpoints(context.Background(), s, services, endpoints)
+			So(err, ShouldBeNil)
+			e := &evaluation{inventory: &inventory{probes: probes}}
+
+			Convey("Then reachability is reported per service type, region and interface", func() {
+				up := probesUp(e)
+				So(len(up), ShouldEqual, 4)
+				So(up[0].dynamic, ShouldResemble, []string{"compute", "RegionOne", "public"})
+				So(up[0].data, ShouldEqual, 0)
+				So(up[0].tags["endpoint_id"], ShouldEqual, "e3,e5")
+				So(up[0].tags["service_name"], ShouldEqual, "nova")
+				So(up[0].tags["url"], ShouldContainSubstring, broken.URL+"/v2.1/%(tenant_id)s")
+				So(value(up, "compute", unknownRegion, "internal").data, ShouldEqual, 0)
+				So(value(up, "identity", "RegionOne", "public").data, ShouldEqual, 1)
+			})
+
+			Convey("and certificate which cannot be verified makes endpoint down", func() {
+				So(value(probesUp(e), "identity", "RegionOne", "admin").data, ShouldEqual, 0)
+			})
+
+			Convey("and latency is reported for endpoints which responded", func() {
+				latency := probesLatency(e)
+				So(len(latency), ShouldEqual, 2)
+				So(value(latency, "compute", "RegionOne", "public").data, ShouldBeGreaterThan, 0)
+				So(value(latency, "compute", unknownRegion, "internal"), ShouldBeNil)
+			})
+		})
+
+		Convey("When endpoints are probed without certificate verification", func() {
+			s.probeInsecureSkipVerify = true
+			probes, err := probeEndpoints(context.Background(), s, services, endpoints)
+			So(err, ShouldBeNil)
+			e := &evaluation{inventory: &inventory{probes: probes}}
+
+			Convey("Then redirect is a response of reachable endpoint", func() {
+				So(value(probesUp(e), "identity", "RegionOne", "admin").data, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When collection is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := probeEndpoints(ctx, s, services, endpoints)
+
+			Convey("Then error is returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,176 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+// Report holds results of single standalone collection
+type Report struct {
+	Metrics []ReportMetric `json:"metrics"`
+	Timings []Timing       `json:"timings"`
+	Errors  []string       `json:"errors,omitempty"`
+
+	// Collected holds collected metrics as returned by CollectMetrics
+	Collected []plugin.Metric `json:"-"`
+}
+
+// ReportMetric is a single collected metric
+type ReportMetric struct {
+	Namespace string            `json:"namespace"`
+	Data      interface{}       `json:"data"`
+	Unit      string            `json:"unit,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+}
+
+// Timing is duration of single call to the plugin
+type Timing struct {
+	Call     string        `json:"call"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// LoadConfig reads plugin configuration from JSON file. The file holds either config items of the plugin
+// or snapteld global config, from which items of keystone collector (control.plugins.collector.keystone.all) are taken.
+func LoadConfig(path string) (plugin.Config, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var global struct {
+		Control struct {
+			Plugins struct {
+				Collector map[string]struct {
+					All plugin.Config `json:"all"`
+				} `json:"collector"`
+			} `json:"plugins"`
+		} `json:"control"`
+	}
+	if err := json.Unmarshal(content, &global); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %v", path, err)
+	}
+	if items, ok := global.Control.Plugins.Collector[name]; ok {
+		return items.All, nil
+	}
+
+	cfg := plugin.Config{}
+	if err := json.Unmarshal(content, &cfg); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// RunOnce lists and collects all metrics once with given configuration using new collector
+func RunOnce(cfg plugin.Config) (*Report, error) {
+	return New().Collect(cfg)
+}
+
+// Collect lists and collects all metrics with given configuration, the way snapteld would do in a task
+// requesting every metric. Returned error reports any failure, including failures of single data sources
+// which do not prevent the rest of metrics from being collected.
+func (c *collector) Collect(cfg plugin.Config) (*Report, error) {
+	report := &Report{Metrics: []ReportMetric{}}
+
+	start := time.Now()
+	mts, err := c.GetMetricTypes(cfg)
+	report.Timings = append(report.Timings, Timing{Call: "GetMetricTypes", Duration: time.Since(start)})
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report, err
+	}
+
+	for i := range mts {
+		mts[i].Config = cfg
+	}
+
+	c.mutex.Lock()
+	start = time.Now()
+	metrics, err := c.collectMetrics(mts)
+	report.Timings = append(report.Timings, Timing{Call: "CollectMetrics", Duration: time.Since(start)})
+	if err == nil {
+		err = c.lastErr
+	}
+	c.mutex.Unlock()
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	}
+
+	report.Collected = metrics
+	for _, m := range metrics {
+		report.Metrics = append(report.Metrics, ReportMetric{
+			Namespace: m.Namespace.String(),
+			Data:      m.Data,
+			Unit:      m.Unit,
+			Tags:      m.Tags,
+		})
+	}
+
+	return report, err
+}
+
+// WriteJSON writes report as indented JSON document
+func (r *Report) WriteJSON(w io.Writer) error {
+	out, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", out)
+	return err
+}
+
+// WriteTable writes report as human readable table of metrics followed by timings and errors
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tDATA\tUNIT\tTAGS")
+	for _, m := range r.Metrics {
+		fmt.Fprintf(tw, "%s\t%v\t%s\t%s\n", m.Namespace, m.Data, m.Unit, formatTags(m.Tags))
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "CALL\tDURATION")
+	for _, t := range r.Timings {
+		fmt.Fprintf(tw, "%s\t%v\n", t.Call, t.Duration)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, e := range r.Errors {
+		if _, err := fmt.Fprintf(w, "\nERROR: %s\n", e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatTags returns tags as sorted list of key=value pairs
+func formatTags(tags map[string]string) string {
+	pairs := []string{}
+	for key, value := range tags {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,141 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	th "github.com/rackspace/gophercloud/testhelper"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLoadConfig(t *testing.T) {
+	Convey("Given directory with config files", t, func() {
+		dir, err := ioutil.TempDir("", "keystone")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		write := func(name, content string) string {
+			path := filepath.Join(dir, name)
+			So(ioutil.WriteFile(path, []byte(content), 0600), ShouldBeNil)
+			return path
+		}
+
+		Convey("When file holds plugin config items", func() {
+			cfg, err := LoadConfig(write("plugin.json", `{"admin_user": "admin", "max_retries": 2}`))
+
+			Convey("Then items are read", func() {
+				So(err, ShouldBeNil)
+				So(cfg["admin_user"], ShouldEqual, "admin")
+				So(cfg["max_retries"], ShouldEqual, 2)
+			})
+		})
+
+		Convey("When file holds snapteld global config", func() {
+			cfg, err := LoadConfig("../examples/cfg/cfg.json")
+
+			Convey("Then items of keystone collector are read", func() {
+				So(err, ShouldBeNil)
+				So(cfg["admin_user"], ShouldEqual, "admin")
+				So(cfg, ShouldNotContainKey, "control")
+			})
+		})
+
+		Convey("When file is not valid JSON", func() {
+			_, err := LoadConfig(write("broken.json", `{"admin_user": `))
+
+			Convey("Then error is reported", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestRunOnce() {
+	Convey("Given valid config", s.T(), func() {
+		cfg := setupCfg(th.Endpoint(), "me", "secret", "admin")
+
+		Convey("When RunOnce() is called", func() {
+			report, err := RunOnce(cfg)
+
+			Convey("Then all metrics are collected without errors", func() {
+				So(err, ShouldBeNil)
+				So(report.Errors, ShouldBeEmpty)
+				// metrics known only to authentication API in v3 have no dynamic values with v2,
+				// metrics read from local files are not listed without their files configured,
+				// changes since previous collection are not known in the first one
+				static := 0
+				for _, m := range metricDefs {
+					ns := m.namespace.String()
+					if strings.HasSuffix(ns, "_added_count") || strings.HasSuffix(ns, "_removed_count") {
+						continue
+					}
+					if len(dynamicElements(m.namespace)) == 0 && m.enabled == nil {
+						static++
+					}
+				}
+				So(len(report.Metrics), ShouldBeGreaterThan, static)
+
+				namespaces := []string{}
+				for _, m := range report.Metrics {
+					namespaces = append(namespaces, m.Namespace)
+				}
+				So(namespaces, ShouldContain, "/intel/openstack/keystone/demo/users_count")
+			})
+
+			Convey("and timing of each call is reported", func() {
+				So(len(report.Timings), ShouldEqual, 2)
+				So(report.Timings[0].Call, ShouldEqual, "GetMetricTypes")
+				So(report.Timings[1].Call, ShouldEqual, "CollectMetrics")
+			})
+
+			Convey("and report can be written as JSON", func() {
+				var buf bytes.Buffer
+				So(report.WriteJSON(&buf), ShouldBeNil)
+
+				decoded := Report{}
+				So(json.Unmarshal(buf.Bytes(), &decoded), ShouldBeNil)
+				So(len(decoded.Metrics), ShouldEqual, len(report.Metrics))
+			})
+
+			Convey("and report can be written as table", func() {
+				var buf bytes.Buffer
+				So(report.WriteTable(&buf), ShouldBeNil)
+				So(buf.String(), ShouldContainSubstring, "/intel/openstack/keystone/demo/users_count")
+				So(buf.String(), ShouldContainSubstring, "tenant_id=11111")
+				So(buf.String(), ShouldContainSubstring, "CollectMetrics")
+			})
+		})
+	})
+
+	Convey("Given config with invalid endpoint", s.T(), func() {
+		cfg := setupCfg("keystone", "me", "secret", "admin")
+
+		Convey("When RunOnce() is called", func() {
+			report, err := RunOnce(cfg)
+
+			Convey("Then error is reported", func() {
+				So(err, ShouldNotBeNil)
+				So(report.Errors, ShouldHaveLength, 1)
+			})
+		})
+	})
+}
@@ -0,0 +1,202 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// stateVersion is the version of state file format, state files of other versions are discarded
+const stateVersion = 1
+
+// state is what the collector remembers across plugin restarts
+type state struct {
+	Version  int       `json:"version"`
+	SavedAt  time.Time `json:"saved_at"`
+	Endpoint string    `json:"endpoint"`
+
+	// Entities holds IDs of entities gathered in the last collection, by entity name
+	Entities map[string][]string `json:"entities"`
+
+	// Auth describes the last successful authentication, the token itself is never saved
+	Auth *authState `json:"auth,omitempty"`
+
+	// AuditLog is the position in audit log read so far
+	AuditLog *tailState `json:"audit_log,omitempty"`
+
+	// AccessLog is the position in access log read so far
+	AccessLog *tailState `json:"access_log,omitempty"`
+}
+
+// authState holds metadata of the last successful authentication
+type authState struct {
+	IdentityEndpoint string    `json:"identity_endpoint"`
+	AuthenticatedAt  time.Time `json:"authenticated_at"`
+}
+
+// statePath returns path of state file of given Keystone and credentials within state_dir,
+// so that tasks collecting from different clouds can share the directory
+func (s *settings) statePath() string {
+	return filepath.Join(s.stateDir, "keystone-"+s.cloudID()[:12]+".json")
+}
+
+// cloudID identifies Keystone and credentials used to access it
+func (s *settings) cloudID() string {
+	id := sha1.Sum([]byte(s.endpoint + "\x00" + s.user + "\x00" + s.tenant + "\x00" + s.tenantID + "\x00" +
+		s.domainName + "\x00" + s.domainID))
+	return hex.EncodeToString(id[:])
+}
+
+// loadState restores state saved by previous run of the plugin, once per plugin run.
+// Missing state is not an error; corrupted state is moved aside and state of other version is discarded,
+// in both cases collector starts over as if there was no state.
+func (c *collector) loadState(s *settings) {
+	if s.stateDir == "" || c.stateLoaded {
+		return
+	}
+	c.stateLoaded = true
+
+	path := s.statePath()
+	st, err := readState(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return
+		}
+		log.WithField("plugin", name).Warnf("cannot read state %s, starting over: %v", path, err)
+		if err == errStateCorrupted {
+			moveAside(path, path+".corrupted")
+		}
+		return
+	}
+
+	if st.Version != stateVersion {
+		// state of other version is kept, so that it is not lost when the plugin is downgraded
+		log.WithField("plugin", name).Warnf("state %s has version %d, expected %d, starting over", path, st.Version, stateVersion)
+		moveAside(path, fmt.Sprintf("%s.v%d", path, st.Version))
+		return
+	}
+	if st.Endpoint != s.endpoint {
+		log.WithField("plugin", name).Warnf("state %s belongs to %s, starting over", path, st.Endpoint)
+		return
+	}
+
+	c.previous = map[string]map[string]bool{}
+	for entity, ids := range st.Entities {
+		c.previous[entity] = map[string]bool{}
+		for _, id := range ids {
+			c.previous[entity][id] = true
+		}
+	}
+	c.auth = st.Auth
+	c.auditState = st.AuditLog
+	c.accessState = st.AccessLog
+}
+
+// errStateCorrupted is returned for state file which cannot be decoded, e.g. truncated one
+var errStateCorrupted = errors.New("state is corrupted")
+
+// readState reads state file at given path
+func readState(path string) (*state, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &state{}
+	if err := json.Unmarshal(data, st); err != nil || st.Version == 0 {
+		return nil, errStateCorrupted
+	}
+	return st, nil
+}
+
+// moveAside renames state file which cannot be used, so that it can be inspected later
+func moveAside(path, newPath string) {
+	if err := os.Rename(path, newPath); err != nil {
+		log.WithField("plugin", name).Warn(err)
+	}
+}
+
+// saveState saves state of the collector, failure is logged and does not fail the collection
+func (c *collector) saveState(s *settings) {
+	if s.stateDir == "" {
+		return
+	}
+
+	st := &state{
+		Version:   stateVersion,
+		SavedAt:   time.Now().UTC(),
+		Endpoint:  s.endpoint,
+		Entities:  map[string][]string{},
+		Auth:      c.auth,
+		AuditLog:  savedPosition(c.auditTail, c.auditState),
+		AccessLog: savedPosition(c.accessTail, c.accessState),
+	}
+	for entity, ids := range c.previous {
+		st.Entities[entity] = []string{}
+		for id := range ids {
+			st.Entities[entity] = append(st.Entities[entity], id)
+		}
+		sort.Strings(st.Entities[entity])
+	}
+
+	if err := writeState(s.statePath(), st); err != nil {
+		log.WithField("plugin", name).Warnf("cannot save state: %v", err)
+	}
+}
+
+// writeState writes state atomically: to temporary file in the same directory, which then replaces the state file.
+// Readers see either the previous or the new state, never partially written one.
+func writeState(path string, st *state) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".keystone-state-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,127 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	th "github.com/rackspace/gophercloud/testhelper"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+)
+
+func TestState(t *testing.T) {
+	Convey("Given state directory", t, func() {
+		dir, err := ioutil.TempDir("", "keystone")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		s := &settings{endpoint: "http://keystone:5000", user: "admin", stateDir: filepath.Join(dir, "state")}
+
+		Convey("When state is saved by collector", func() {
+			c := &collector{
+				previous: map[string]map[string]bool{"users": {"u2": true, "u1": true}},
+				auth:     &authState{IdentityEndpoint: "http://keystone:5000/v3/"},
+			}
+			c.saveState(s)
+
+			Convey("Then it is restored by new collector", func() {
+				restored := &collector{}
+				restored.loadState(s)
+				So(restored.previous, ShouldResemble, c.previous)
+				So(restored.auth.IdentityEndpoint, ShouldEqual, "http://keystone:5000/v3/")
+			})
+
+			Convey("and only the state file is left in the directory", func() {
+				files, err := ioutil.ReadDir(s.stateDir)
+				So(err, ShouldBeNil)
+				So(len(files), ShouldEqual, 1)
+				So(filepath.Join(s.stateDir, files[0].Name()), ShouldEqual, s.statePath())
+				So(files[0].Mode().Perm(), ShouldEqual, os.FileMode(0600))
+			})
+
+			Convey("and state of other Keystone is kept in separate file", func() {
+				other := *s
+				other.endpoint = "http://other:5000"
+				So(other.statePath(), ShouldNotEqual, s.statePath())
+
+				restored := &collector{}
+				restored.loadState(&other)
+				So(restored.previous, ShouldBeNil)
+			})
+		})
+
+		Convey("When state file is corrupted", func() {
+			So(os.MkdirAll(s.stateDir, 0700), ShouldBeNil)
+			So(ioutil.WriteFile(s.statePath(), []byte(`{"version": 1, "entities": {"users": [`), 0600), ShouldBeNil)
+
+			c := &collector{}
+			c.loadState(s)
+
+			Convey("Then collector starts over and corrupted file is moved aside", func() {
+				So(c.previous, ShouldBeNil)
+				_, err := os.Stat(s.statePath() + ".corrupted")
+				So(err, ShouldBeNil)
+				_, err = os.Stat(s.statePath())
+				So(os.IsNotExist(err), ShouldBeTrue)
+			})
+		})
+
+		Convey("When state file has other version", func() {
+			So(os.MkdirAll(s.stateDir, 0700), ShouldBeNil)
+			So(ioutil.WriteFile(s.statePath(), []byte(`{"version": 99, "entities": {"users": ["u1"]}}`), 0600), ShouldBeNil)
+
+			c := &collector{}
+			c.loadState(s)
+
+			Convey("Then collector starts over and the file is kept for other version", func() {
+				So(c.previous, ShouldBeNil)
+				_, err := os.Stat(s.statePath() + ".v99")
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func (s *CollectorSuite) TestCollectMetricsWithState() {
+	Convey("Given churn metric type and config with state directory", s.T(), func() {
+		dir, err := ioutil.TempDir("", "keystone")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		cfg := setupCfg(th.Endpoint(), "me", "secret", "admin")
+		cfg["state_dir"] = dir
+		mt := plugin.Metric{
+			Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "users_removed_count"),
+			Config:    cfg}
+
+		Convey("When metrics are collected before and after plugin restart", func() {
+			_, err := New().CollectMetrics([]plugin.Metric{mt})
+			So(err, ShouldBeNil)
+
+			mts, err := New().CollectMetrics([]plugin.Metric{mt})
+
+			Convey("Then changes are reported already in the first collection after restart", func() {
+				So(err, ShouldBeNil)
+				So(len(mts), ShouldEqual, 1)
+				So(mts[0].Data, ShouldEqual, 0)
+			})
+		})
+	})
+}
@@ -0,0 +1,228 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"os"
+)
+
+// fingerprintSize is the number of bytes from the beginning of file identifying it across restarts
+const fingerprintSize = 256
+
+// tailer reads lines appended to a local file between collections. Rotation by renaming the file
+// is detected by comparing the open file with the one at path, rotation by truncation by file size.
+// It is not safe for concurrent use, collections reading it are serialized by the collector.
+type tailer struct {
+	path string
+	file *os.File
+
+	// offset is the position in file up to which it was read, -1 makes reading start at the end
+	offset int64
+
+	// partial holds the last line which was not terminated yet
+	partial []byte
+
+	// fingerprint identifies file whose offset was restored, it is checked when the file is opened
+	fingerprint *tailFingerprint
+}
+
+// tailState is the position of tailer saved across restarts
+type tailState struct {
+	Path        string           `json:"path"`
+	Offset      int64            `json:"offset"`
+	Fingerprint *tailFingerprint `json:"fingerprint,omitempty"`
+}
+
+// tailFingerprint is the hash of the beginning of file
+type tailFingerprint struct {
+	Size int    `json:"size"`
+	Hash string `json:"hash"`
+}
+
+// newTailer creates tailer of file at given path, which starts reading at the end of file
+// so that lines written before the plugin was started are not read
+func newTailer(path string) *tailer {
+	return &tailer{path: path, offset: -1}
+}
+
+// tailerOf returns tailer of file at given path, t is reused unless it reads other file.
+// New tailer resumes reading at position restored from state.
+func tailerOf(t *tailer, path string, restored *tailState) *tailer {
+	if t != nil && t.path == path {
+		return t
+	}
+	t = newTailer(path)
+	t.restore(restored)
+	return t
+}
+
+// savedPosition returns position of tailer to be saved, or the restored one when file was not opened yet
+func savedPosition(t *tailer, restored *tailState) *tailState {
+	if t != nil {
+		if position := t.state(); position != nil {
+			return position
+		}
+	}
+	return restored
+}
+
+// restore makes tailer resume reading at saved position, unless the file was replaced meanwhile
+func (t *tailer) restore(st *tailState) {
+	if st == nil || st.Path != t.path || t.file != nil {
+		return
+	}
+	t.offset = st.Offset
+	t.fingerprint = st.Fingerprint
+}
+
+// state returns position of tailer to be saved, lines which are not terminated yet are read again after restart
+func (t *tailer) state() *tailState {
+	if t.file == nil {
+		return nil
+	}
+	offset := t.offset - int64(len(t.partial))
+	return &tailState{Path: t.path, Offset: offset, Fingerprint: fingerprint(t.file, offset)}
+}
+
+// readLines returns complete lines appended to the file since previous call. When the file was rotated,
+// the rest of rotated file is read first, followed by the new file from its beginning.
+// Missing file is not an error, it is expected to appear.
+func (t *tailer) readLines() ([]string, error) {
+	if t.file == nil {
+		if err := t.open(); err != nil {
+			if os.IsNotExist(err) {
+				return []string{}, nil
+			}
+			return nil, err
+		}
+	}
+
+	lines, err := t.read()
+	if err != nil {
+		return lines, err
+	}
+
+	info, err := os.Stat(t.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// rotated, new file is not created yet
+			return lines, nil
+		}
+		return lines, err
+	}
+	current, err := t.file.Stat()
+	if err != nil {
+		return lines, err
+	}
+
+	switch {
+	case !os.SameFile(info, current):
+		t.file.Close()
+		t.file = nil
+		t.offset = 0
+		t.partial = nil
+		if err := t.open(); err != nil {
+			return lines, err
+		}
+	case info.Size() < t.offset:
+		if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+			return lines, err
+		}
+		t.offset = 0
+		t.partial = nil
+	default:
+		return lines, nil
+	}
+
+	more, err := t.read()
+	return append(lines, more...), err
+}
+
+// open opens the file and moves to the position to read from
+func (t *tailer) open() error {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	switch {
+	case t.offset < 0:
+		t.offset = info.Size()
+	case t.offset > info.Size():
+		t.offset = 0
+	case t.fingerprint != nil:
+		if f := fingerprint(file, int64(t.fingerprint.Size)); f == nil || *f != *t.fingerprint {
+			t.offset = 0
+		}
+	}
+	t.fingerprint = nil
+
+	if _, err := file.Seek(t.offset, io.SeekStart); err != nil {
+		file.Close()
+		return err
+	}
+	t.file = file
+	return nil
+}
+
+// read reads the file up to its end and returns complete lines
+func (t *tailer) read() ([]string, error) {
+	lines := []string{}
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := t.file.Read(buf)
+		if n > 0 {
+			t.offset += int64(n)
+			t.partial = append(t.partial, buf[:n]...)
+			for {
+				i := bytes.IndexByte(t.partial, '\n')
+				if i < 0 {
+					break
+				}
+				lines = append(lines, string(bytes.TrimRight(t.partial[:i], "\r")))
+				t.partial = t.partial[i+1:]
+			}
+		}
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
+	}
+}
+
+// fingerprint returns hash of at most fingerprintSize bytes of the beginning of file, which is limited to given size
+func fingerprint(file *os.File, size int64) *tailFingerprint {
+	if size > fingerprintSize {
+		size = fingerprintSize
+	}
+	buf := make([]byte, size)
+	n, err := file.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return nil
+	}
+	hash := sha1.Sum(buf[:n])
+	return &tailFingerprint{Size: n, Hash: hex.EncodeToString(hash[:])}
+}
@@ -0,0 +1,129 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTailer(t *testing.T) {
+	Convey("Given log file with existing lines", t, func() {
+		dir, err := ioutil.TempDir("", "keystone")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "audit.log")
+		So(ioutil.WriteFile(path, []byte("old 1\nold 2\n"), 0600), ShouldBeNil)
+		appendLines := func(path, content string) {
+			file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+			So(err, ShouldBeNil)
+			_, err = file.WriteString(content)
+			So(err, ShouldBeNil)
+			So(file.Close(), ShouldBeNil)
+		}
+
+		tail := newTailer(path)
+		lines, err := tail.readLines()
+		So(err, ShouldBeNil)
+
+		Convey("Then lines written before tailing started are skipped", func() {
+			So(lines, ShouldBeEmpty)
+		})
+
+		Convey("When lines are appended", func() {
+			appendLines(path, "new 1\nnew 2\npart")
+
+			Convey("Then complete lines are read and partial line waits for its end", func() {
+				lines, err := tail.readLines()
+				So(err, ShouldBeNil)
+				So(lines, ShouldResemble, []string{"new 1", "new 2"})
+
+				appendLines(path, "ial\n")
+				lines, err = tail.readLines()
+				So(err, ShouldBeNil)
+				So(lines, ShouldResemble, []string{"partial"})
+			})
+		})
+
+		Convey("When file is rotated by renaming", func() {
+			appendLines(path, "before rotation\n")
+			So(os.Rename(path, path+".1"), ShouldBeNil)
+			appendLines(path+".1", "late write\n")
+			appendLines(path, "after rotation\n")
+
+			Convey("Then the rest of rotated file is read, followed by the new file", func() {
+				lines, err := tail.readLines()
+				So(err, ShouldBeNil)
+				So(lines, ShouldResemble, []string{"before rotation", "late write", "after rotation"})
+			})
+		})
+
+		Convey("When file is truncated", func() {
+			So(ioutil.WriteFile(path, []byte("x\n"), 0600), ShouldBeNil)
+
+			Convey("Then it is read from its beginning", func() {
+				lines, err := tail.readLines()
+				So(err, ShouldBeNil)
+				So(lines, ShouldResemble, []string{"x"})
+			})
+		})
+
+		Convey("When position is restored by new tailer", func() {
+			appendLines(path, "new 1\n")
+			_, err := tail.readLines()
+			So(err, ShouldBeNil)
+			saved := tail.state()
+			appendLines(path, "while stopped\n")
+
+			restored := newTailer(path)
+			restored.restore(saved)
+
+			Convey("Then lines written meanwhile are read", func() {
+				lines, err := restored.readLines()
+				So(err, ShouldBeNil)
+				So(lines, ShouldResemble, []string{"while stopped"})
+			})
+		})
+
+		Convey("When file was replaced while position was saved", func() {
+			saved := tail.state()
+			So(os.Remove(path), ShouldBeNil)
+			So(ioutil.WriteFile(path, []byte("other 1\nother 2\nother 3\n"), 0600), ShouldBeNil)
+
+			restored := newTailer(path)
+			restored.restore(saved)
+
+			Convey("Then the new file is read from its beginning", func() {
+				lines, err := restored.readLines()
+				So(err, ShouldBeNil)
+				So(lines, ShouldResemble, []string{"other 1", "other 2", "other 3"})
+			})
+		})
+
+		Convey("When file does not exist", func() {
+			lines, err := newTailer(filepath.Join(dir, "missing.log")).readLines()
+
+			Convey("Then nothing is read without error", func() {
+				So(err, ShouldBeNil)
+				So(lines, ShouldBeEmpty)
+			})
+		})
+	})
+}
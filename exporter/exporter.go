@@ -0,0 +1,227 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package exporter serves metrics gathered by keystone collector in Prometheus text exposition format
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+	log "github.com/sirupsen/logrus"
+)
+
+// ContentType of Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// CollectFunc gathers metrics once. Metrics collected despite of failures are expected to be returned with error.
+type CollectFunc func() ([]plugin.Metric, error)
+
+// Handler serves metrics gathered with its CollectFunc on every scrape.
+// Scrapes are serialized, so the collection engine is never run concurrently.
+type Handler struct {
+	collect CollectFunc
+	prefix  string
+	mutex   sync.Mutex
+}
+
+// NewHandler creates handler which serves metrics gathered with collect.
+// Names of exporter's own metrics (scrape success and duration) start with given prefix.
+func NewHandler(collect CollectFunc, prefix string) *Handler {
+	return &Handler{collect: collect, prefix: prefix}
+}
+
+// ServeHTTP collects metrics and writes them in Prometheus text format
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	start := time.Now()
+	metrics, err := h.collect()
+	duration := time.Since(start)
+
+	success := 1
+	if err != nil {
+		success = 0
+		log.WithField("exporter", "prometheus").Warn(err)
+	}
+
+	var buf bytes.Buffer
+	Write(&buf, metrics)
+	writeFamily(&buf, h.prefix+"_scrape_success", "Whether all Keystone requests of the scrape succeeded", []sample{{value: float64(success)}})
+	writeFamily(&buf, h.prefix+"_scrape_duration_seconds", "Duration of the scrape", []sample{{value: duration.Seconds()}})
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Write(buf.Bytes())
+}
+
+// sample is a single value of metric family
+type sample struct {
+	labels map[string]string
+	value  float64
+}
+
+// family groups samples with the same metric name
+type family struct {
+	name    string
+	help    string
+	samples []sample
+}
+
+// Write writes metrics in Prometheus text format. Metric name is made of static namespace elements,
+// dynamic elements and tags become labels. Metrics whose data is not numeric are left out.
+func Write(w io.Writer, metrics []plugin.Metric) {
+	families := map[string]*family{}
+	names := []string{}
+
+	for _, m := range metrics {
+		value, ok := toFloat(m.Data)
+		if !ok {
+			continue
+		}
+
+		static := []string{}
+		labels := map[string]string{}
+		for _, element := range m.Namespace {
+			if element.Name != "" {
+				labels[sanitize(element.Name)] = element.Value
+			} else {
+				static = append(static, element.Value)
+			}
+		}
+		for key, tag := range m.Tags {
+			labels[sanitize(key)] = tag
+		}
+
+		name := sanitize(strings.Join(static, "_"))
+		f, ok := families[name]
+		if !ok {
+			f = &family{name: name, help: m.Description}
+			families[name] = f
+			names = append(names, name)
+		}
+		f.samples = append(f.samples, sample{labels: labels, value: value})
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		f := families[name]
+		writeFamily(w, f.name, f.help, f.samples)
+	}
+}
+
+// writeFamily writes HELP and TYPE lines followed by samples of single gauge
+func writeFamily(w io.Writer, name, help string, samples []sample) {
+	if help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(help))
+	}
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+	for _, s := range samples {
+		fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(s.labels), formatValue(s.value))
+	}
+}
+
+// formatLabels returns labels sorted by name in Prometheus syntax, or empty string when there are no labels
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := []string{}
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := []string{}
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, key, escapeLabel(labels[key])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// formatValue returns value in Prometheus syntax
+func formatValue(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// toFloat converts numeric and boolean metric data to float64
+func toFloat(data interface{}) (float64, bool) {
+	switch value := data.(type) {
+	case int:
+		return float64(value), true
+	case int32:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case uint64:
+		return float64(value), true
+	case float32:
+		return float64(value), true
+	case float64:
+		return value, true
+	case bool:
+		if value {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// sanitize replaces characters which are not allowed in metric and label names with underscore
+func sanitize(name string) string {
+	out := []rune{}
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			out = append(out, r)
+		case r >= '0' && r <= '9' && i > 0:
+			out = append(out, r)
+		default:
+			out = append(out, '_')
+		}
+	}
+	return string(out)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
@@ -0,0 +1,99 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exporter
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var metrics = []plugin.Metric{
+	{
+		Namespace:   plugin.NewNamespace("intel", "openstack", "keystone").AddDynamicElement("tenant_name", "name of tenant").AddStaticElement("users_count"),
+		Description: "Total number of users for given tenant",
+		Data:        3,
+		Tags:        map[string]string{"tenant_id": "11111", "endpoint": "http://keystone:5000/"},
+	},
+	{
+		Namespace:   plugin.NewNamespace("intel", "openstack", "keystone", "total_tenants_count"),
+		Description: "Total number of tenants",
+		Data:        2,
+	},
+	{
+		Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "rate_limit_wait_ms"),
+		Data:      float64(12.5),
+	},
+	{
+		Namespace: plugin.NewNamespace("intel", "openstack", "keystone", "catalog"),
+		Data:      `{"services": []}`,
+	},
+}
+
+func init() {
+	metrics[0].Namespace[3].Value = "demo \"one\""
+}
+
+func TestWrite(t *testing.T) {
+	Convey("Given collected metrics", t, func() {
+
+		Convey("When they are written in Prometheus format", func() {
+			var buf bytes.Buffer
+			Write(&buf, metrics)
+			out := buf.String()
+
+			Convey("Then static namespace elements make metric name and dynamic elements and tags make labels", func() {
+				So(out, ShouldContainSubstring, "# HELP intel_openstack_keystone_users_count Total number of users for given tenant\n")
+				So(out, ShouldContainSubstring, "# TYPE intel_openstack_keystone_users_count gauge\n")
+				So(out, ShouldContainSubstring, `intel_openstack_keystone_users_count{endpoint="http://keystone:5000/",tenant_id="11111",tenant_name="demo \"one\""} 3`+"\n")
+			})
+
+			Convey("and metrics without dynamic elements and tags have no labels", func() {
+				So(out, ShouldContainSubstring, "intel_openstack_keystone_total_tenants_count 2\n")
+				So(out, ShouldContainSubstring, "intel_openstack_keystone_rate_limit_wait_ms 12.5\n")
+			})
+
+			Convey("and metrics which are not numeric are left out", func() {
+				So(out, ShouldNotContainSubstring, "catalog")
+			})
+		})
+	})
+}
+
+func TestHandler(t *testing.T) {
+	Convey("Given handler whose collection partially fails", t, func() {
+		handler := NewHandler(func() ([]plugin.Metric, error) {
+			return metrics[1:2], errors.New("users: timeout")
+		}, "keystone_exporter")
+
+		Convey("When /metrics is scraped", func() {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+			body, _ := ioutil.ReadAll(recorder.Body)
+
+			Convey("Then collected metrics are served with failed scrape reported", func() {
+				So(recorder.Header().Get("Content-Type"), ShouldEqual, ContentType)
+				So(string(body), ShouldContainSubstring, "intel_openstack_keystone_total_tenants_count 2\n")
+				So(string(body), ShouldContainSubstring, "keystone_exporter_scrape_success 0\n")
+				So(string(body), ShouldContainSubstring, "# TYPE keystone_exporter_scrape_duration_seconds gauge\n")
+			})
+		})
+	})
+}
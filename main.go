@@ -15,23 +15,110 @@ limitations under the License.
 package main
 
 import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
 
-	"github.com/intelsdi-x/snap/control/plugin"
+	"github.com/intelsdi-x/snap-plugin-lib-go/v1/plugin"
 
 	"github.com/intelsdi-x/snap-plugin-collector-keystone/collector"
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/exporter"
 )
 
 func main() {
+	for _, arg := range os.Args[1:] {
+		if arg == "--collect" || arg == "-collect" {
+			os.Exit(collectOnce(os.Args[1:], os.Stdout, os.Stderr))
+		}
+		if arg == "--serve" || arg == "-serve" {
+			os.Exit(serve(os.Args[1:], os.Stderr))
+		}
+	}
 
 	plg := collector.New()
 	if plg == nil {
 		panic("Keystone collector could not be initialized")
 	}
 
-	plugin.Start(
-		collector.Meta(),
-		plg,
-		os.Args[1],
-	)
+	name, version, opts := collector.Meta()
+	plugin.StartCollector(plg, name, version, opts...)
+}
+
+// collectOnce runs standalone collection without snapteld and prints its results.
+// It returns exit status of the plugin, which is not zero when anything failed.
+func collectOnce(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("collect", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Bool("collect", true, "collect all metrics once and exit")
+	configPath := flags.String("config", "", "path to JSON file with plugin config or snapteld global config")
+	format := flags.String("format", "table", "output format: json or table")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *configPath == "" || (*format != "json" && *format != "table") {
+		flags.Usage()
+		return 2
+	}
+
+	cfg, err := collector.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	report, collectErr := collector.RunOnce(cfg)
+	if *format == "json" {
+		err = report.WriteJSON(stdout)
+	} else {
+		err = report.WriteTable(stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	if collectErr != nil {
+		return 1
+	}
+	return 0
+}
+
+// serve runs HTTP server exposing metrics in Prometheus text format on /metrics.
+// Metrics are collected on every scrape with a single collector, so its caches, rate limiter and
+// circuit breaker are shared between scrapes.
+func serve(args []string, stderr io.Writer) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Bool("serve", true, "serve metrics in Prometheus text format")
+	address := flags.String("listen", ":9161", "address HTTP server with /metrics endpoint listens on")
+	configPath := flags.String("config", "", "path to JSON file with plugin config or snapteld global config")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *configPath == "" {
+		flags.Usage()
+		return 2
+	}
+
+	cfg, err := collector.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	c := collector.New()
+	handler := exporter.NewHandler(func() ([]plugin.Metric, error) {
+		report, err := c.Collect(cfg)
+		return report.Collected, err
+	}, "keystone_exporter")
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", handler)
+	if err := http.ListenAndServe(*address, mux); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
 }
@@ -0,0 +1,48 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package appcredentials
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/rackspace/gophercloud"
+)
+
+// ApplicationCredential represents Keystone v3 application credential, its secret is never returned by Keystone
+type ApplicationCredential struct {
+	ID        string `json:"id" mapstructure:"id"`
+	Name      string `json:"name" mapstructure:"name"`
+	ProjectID string `json:"project_id" mapstructure:"project_id"`
+	// ExpiresAt is empty for application credentials which do not expire
+	ExpiresAt string `json:"expires_at" mapstructure:"expires_at"`
+}
+
+// ListResult represents the result of a list operation.
+type ListResult struct {
+	gophercloud.Result
+}
+
+// Extract will get list of application credentials out of the ListResult object.
+func (r ListResult) Extract() ([]ApplicationCredential, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+
+	var resp struct {
+		ApplicationCredentials []ApplicationCredential `json:"application_credentials" mapstructure:"application_credentials"`
+	}
+
+	err := mapstructure.Decode(r.Body, &resp)
+
+	return resp.ApplicationCredentials, err
+}
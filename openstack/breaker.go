@@ -0,0 +1,167 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of sending request while circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open, requests to Keystone are suspended")
+
+// CircuitState represents state of circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed means requests are sent to Keystone
+	CircuitClosed CircuitState = iota
+	// CircuitHalfOpen means single probe request is let through to check if Keystone recovered
+	CircuitHalfOpen
+	// CircuitOpen means requests fail fast without being sent to Keystone
+	CircuitOpen
+)
+
+// String returns name of circuit state
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half-open"
+	case CircuitOpen:
+		return "open"
+	}
+	return "unknown"
+}
+
+// CircuitBreaker stops sending requests to Keystone after number of consecutive failures.
+// Once reset timeout passes, single request is let through and its result decides
+// whether circuit closes again or stays open for another reset timeout.
+type CircuitBreaker struct {
+	threshold    int
+	resetTimeout time.Duration
+
+	mutex    sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker creates circuit breaker which opens after threshold consecutive failures
+// and stays open for resetTimeout
+func NewCircuitBreaker(threshold int, resetTimeout time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	return &CircuitBreaker{
+		threshold:    threshold,
+		resetTimeout: resetTimeout,
+	}
+}
+
+// State returns current state of circuit breaker
+func (b *CircuitBreaker) State() CircuitState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.resetTimeout {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// allow checks if request can be sent
+func (b *CircuitBreaker) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.resetTimeout {
+			return false
+		}
+		b.state = CircuitHalfOpen
+		b.probing = true
+		return true
+	case CircuitHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// success records request which reached Keystone and closes circuit
+func (b *CircuitBreaker) success() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.state = CircuitClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// failure records failed request and opens circuit when threshold is reached
+func (b *CircuitBreaker) failure() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		b.state = CircuitOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// release records request which was cancelled or did not reach Keystone, so its result says nothing about Keystone health
+func (b *CircuitBreaker) release() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.probing = false
+}
+
+// breakerTransport fails fast while circuit breaker is open
+type breakerTransport struct {
+	breaker *CircuitBreaker
+	next    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	switch {
+	case req.Context().Err() != nil, err != nil && !isConnectionError(err):
+		t.breaker.release()
+	case err != nil || isServerError(resp):
+		t.breaker.failure()
+	default:
+		t.breaker.success()
+	}
+
+	return resp, err
+}
@@ -15,19 +15,27 @@ limitations under the License.
 package openstack
 
 import (
+	"context"
+
 	"github.com/rackspace/gophercloud"
 	"github.com/rackspace/gophercloud/openstack"
 )
 
 // Authenticate is used to authenticate user for given tenant. Request is send to provided Keystone endpoint
 // Returns authenticated provider client, which is used as a base for service clients.
-func Authenticate(endpoint, user, password, tenant, domain_name, domain_id string) (*gophercloud.ProviderClient, error) {
+// Options are applied to HTTP transport of returned client, so they affect all requests made with it.
+// Token is scoped to tenant with given tenant_id, or to tenant with given name when tenant_id is empty.
+// Authentication requests are cancelled when ctx is done.
+func Authenticate(ctx context.Context, endpoint, user, password, tenant, tenant_id, domain_name, domain_id string, opts ...Option) (*gophercloud.ProviderClient, error) {
 	authOpts := gophercloud.AuthOptions{
 		IdentityEndpoint: endpoint,
 		Username:         user,
 		Password:         password,
 		TenantName:       tenant,
-		AllowReauth:      true,
+	}
+	if tenant_id != "" {
+		authOpts.TenantName = ""
+		authOpts.TenantID = tenant_id
 	}
 	if domain_name != "" && domain_id == "" {
 		authOpts.DomainName = domain_name
@@ -36,10 +44,35 @@ func Authenticate(endpoint, user, password, tenant, domain_name, domain_id strin
 		authOpts.DomainID = domain_id
 	}
 
-	provider, err := openstack.AuthenticatedClient(authOpts)
+	provider, err := openstack.NewClient(endpoint)
 	if err != nil {
 		return nil, err
 	}
+	transport := newTransport(opts...)
+	provider.HTTPClient.Transport = transport
+	identityBase, identityEndpoint := provider.IdentityBase, provider.IdentityEndpoint
+
+	client := withContext(ctx, provider)
+	if err := openstack.Authenticate(client, authOpts); err != nil {
+		return nil, err
+	}
+
+	// provider keeps the token issued by this call, requests are sent with the current token of session.
+	// Re-authentication is bound to context of the request whose token was rejected.
+	s := &session{token: client.TokenID}
+	s.authenticate = func(ctx context.Context) (string, error) {
+		reauth := withContext(ctx, provider)
+		reauth.TokenID = ""
+		reauth.IdentityBase, reauth.IdentityEndpoint = identityBase, identityEndpoint
+		if err := openstack.Authenticate(reauth, authOpts); err != nil {
+			return "", err
+		}
+		return reauth.TokenID, nil
+	}
+
+	provider.TokenID = client.TokenID
+	provider.EndpointLocator = client.EndpointLocator
+	provider.HTTPClient.Transport = &sessionTransport{session: s, next: transport}
 
 	return provider, nil
 }
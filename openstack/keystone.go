@@ -15,24 +15,43 @@ limitations under the License.
 package openstack
 
 import (
+	"context"
+	"fmt"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/rackspace/gophercloud"
 	"github.com/rackspace/gophercloud/openstack"
 	"github.com/rackspace/gophercloud/openstack/identity/v2/tenants"
-	"github.com/rackspace/gophercloud/openstack/identity/v2/users"
 	"github.com/rackspace/gophercloud/openstack/identity/v3/endpoints"
 	"github.com/rackspace/gophercloud/openstack/identity/v3/services"
 
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/openstack/appcredentials"
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/openstack/credentials"
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/openstack/domains"
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/openstack/limits"
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/openstack/policies"
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/openstack/projects"
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/openstack/registeredlimits"
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/openstack/revocations"
 	"github.com/intelsdi-x/snap-plugin-collector-keystone/openstack/tenantusers"
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/openstack/trusts"
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/openstack/users"
 	"github.com/intelsdi-x/snap-plugin-collector-keystone/types"
 )
 
 // GetTenants is used to retrieve list of available tenant for authenticated user
-func GetAllTenants(provider *gophercloud.ProviderClient) ([]types.Tenant, error) {
+// With authentication API in v3 projects are listed instead, so that domain and parent of each tenant are known.
+func GetAllTenants(ctx context.Context, provider *gophercloud.ProviderClient) ([]types.Tenant, error) {
+	if strings.Contains(provider.IdentityEndpoint, "v3") {
+		return getAllProjects(ctx, provider)
+	}
+
 	tnts := []types.Tenant{}
 
-	client := openstack.NewIdentityV2(provider)
+	client := openstack.NewIdentityV2(withContext(ctx, provider))
 
 	opts := tenants.ListOpts{}
 	pager := tenants.List(client, &opts)
@@ -54,44 +73,368 @@ func GetAllTenants(provider *gophercloud.ProviderClient) ([]types.Tenant, error)
 	return tnts, nil
 }
 
+// getAllProjects is used to retrieve list of available projects together with names of their domains
+func getAllProjects(ctx context.Context, provider *gophercloud.ProviderClient) ([]types.Tenant, error) {
+	tnts := []types.Tenant{}
+
+	client := openstack.NewIdentityV3(withContext(ctx, provider))
+
+	projectList, err := projects.List(client).Extract()
+	if err != nil {
+		return tnts, err
+	}
+
+	domainList, err := domains.List(client).Extract()
+	if err != nil {
+		return tnts, err
+	}
+
+	domainNames := map[string]string{}
+	for _, d := range domainList {
+		domainNames[d.ID] = d.Name
+	}
+
+	for _, p := range projectList {
+		tnts = append(tnts, types.Tenant{
+			Name:       p.Name,
+			ID:         p.ID,
+			DomainID:   p.DomainID,
+			DomainName: domainNames[p.DomainID],
+			ParentID:   p.ParentID,
+			Tags:       p.Tags,
+		})
+	}
+
+	return tnts, nil
+}
+
 // GetAllUsers is used to retrieve list of available users
-func GetAllUsers(provider *gophercloud.ProviderClient) ([]types.User, error) {
+func GetAllUsers(ctx context.Context, provider *gophercloud.ProviderClient) ([]types.User, error) {
 	userList := []types.User{}
 	var client *gophercloud.ServiceClient
 
 	if strings.Contains(provider.IdentityEndpoint, "v3") {
-		client = openstack.NewIdentityV3(provider)
+		client = openstack.NewIdentityV3(withContext(ctx, provider))
 	} else {
-		client = openstack.NewIdentityV2(provider)
-	}
-
-	pager := users.List(client)
-	page, err := pager.AllPages()
-	if err != nil {
-		return userList, err
+		client = openstack.NewIdentityV2(withContext(ctx, provider))
 	}
 
-	usrs, err := users.ExtractUsers(page)
+	usrs, err := users.List(client).Extract()
 	if err != nil {
 		return userList, err
 	}
 
 	for _, u := range usrs {
+		lastActiveAt, err := parseTime(u.LastActiveAt)
+		if err != nil {
+			return userList, fmt.Errorf("cannot parse last_active_at of user %s: %v", u.Name, err)
+		}
+		passwordExpiresAt, err := parseTime(u.PasswordExpiresAt)
+		if err != nil {
+			return userList, fmt.Errorf("cannot parse password_expires_at of user %s: %v", u.Name, err)
+		}
+
 		userList = append(userList, types.User{
-			ID:       u.ID,
-			Name:     u.Name,
-			Username: u.Username,
+			ID:                u.ID,
+			Name:              u.Name,
+			Username:          u.Username,
+			Enabled:           u.Enabled,
+			DomainID:          u.DomainID,
+			LastActiveAt:      lastActiveAt,
+			PasswordExpiresAt: passwordExpiresAt,
 		})
 	}
 
 	return userList, nil
 }
 
+// GetAllDomains is used to retrieve list of available domains.
+// Domains are known only to authentication API in v3, with v2 empty list is returned.
+func GetAllDomains(ctx context.Context, provider *gophercloud.ProviderClient) ([]types.Domain, error) {
+	domainList := []types.Domain{}
+	if !strings.Contains(provider.IdentityEndpoint, "v3") {
+		return domainList, nil
+	}
+
+	client := openstack.NewIdentityV3(withContext(ctx, provider))
+
+	dmns, err := domains.List(client).Extract()
+	if err != nil {
+		return domainList, err
+	}
+
+	for _, d := range dmns {
+		domainList = append(domainList, types.Domain{ID: d.ID, Name: d.Name})
+	}
+
+	return domainList, nil
+}
+
+// GetAllCredentials is used to retrieve list of credentials, e.g. ec2, cert or totp.
+// Credentials are known only to authentication API in v3, with v2 empty list is returned.
+func GetAllCredentials(ctx context.Context, provider *gophercloud.ProviderClient) ([]types.Credential, error) {
+	credentialList := []types.Credential{}
+	if !strings.Contains(provider.IdentityEndpoint, "v3") {
+		return credentialList, nil
+	}
+
+	client := openstack.NewIdentityV3(withContext(ctx, provider))
+
+	creds, err := credentials.List(client).Extract()
+	if err != nil {
+		return credentialList, err
+	}
+
+	for _, c := range creds {
+		credentialList = append(credentialList, types.Credential{
+			ID:        c.ID,
+			Type:      c.Type,
+			UserID:    c.UserID,
+			ProjectID: c.ProjectID,
+		})
+	}
+
+	return credentialList, nil
+}
+
+// GetAllTrusts is used to retrieve list of OS-TRUST trusts.
+// Trusts are known only to authentication API in v3, with v2 empty list is returned.
+func GetAllTrusts(ctx context.Context, provider *gophercloud.ProviderClient) ([]types.Trust, error) {
+	trustList := []types.Trust{}
+	if !strings.Contains(provider.IdentityEndpoint, "v3") {
+		return trustList, nil
+	}
+
+	client := openstack.NewIdentityV3(withContext(ctx, provider))
+
+	trsts, err := trusts.List(client).Extract()
+	if err != nil {
+		return trustList, err
+	}
+
+	for _, t := range trsts {
+		expiresAt, err := parseTime(t.ExpiresAt)
+		if err != nil {
+			return trustList, fmt.Errorf("cannot parse expires_at of trust %s: %v", t.ID, err)
+		}
+
+		trustList = append(trustList, types.Trust{
+			ID:            t.ID,
+			TrustorUserID: t.TrustorUserID,
+			TrusteeUserID: t.TrusteeUserID,
+			ProjectID:     t.ProjectID,
+			Impersonation: t.Impersonation,
+			ExpiresAt:     expiresAt,
+		})
+	}
+
+	return trustList, nil
+}
+
+// GetAllRevocationEvents is used to retrieve list of OS-REVOKE token revocation events.
+// Revocation events are known only to authentication API in v3, with v2 empty list is returned.
+func GetAllRevocationEvents(ctx context.Context, provider *gophercloud.ProviderClient) ([]types.RevocationEvent, error) {
+	eventList := []types.RevocationEvent{}
+	if !strings.Contains(provider.IdentityEndpoint, "v3") {
+		return eventList, nil
+	}
+
+	client := openstack.NewIdentityV3(withContext(ctx, provider))
+
+	evts, err := revocations.List(client).Extract()
+	if err != nil {
+		return eventList, err
+	}
+
+	for _, e := range evts {
+		issuedBefore, err := parseTime(e.IssuedBefore)
+		if err != nil || issuedBefore == nil {
+			return eventList, fmt.Errorf("cannot parse issued_before of revocation event %q", e.IssuedBefore)
+		}
+		expiresAt, err := parseTime(e.ExpiresAt)
+		if err != nil {
+			return eventList, fmt.Errorf("cannot parse expires_at of revocation event: %v", err)
+		}
+		revokedAt, err := parseTime(e.RevokedAt)
+		if err != nil {
+			return eventList, fmt.Errorf("cannot parse revoked_at of revocation event: %v", err)
+		}
+
+		eventList = append(eventList, types.RevocationEvent{
+			UserID:        e.UserID,
+			ProjectID:     e.ProjectID,
+			DomainID:      e.DomainID,
+			RoleID:        e.RoleID,
+			TrustID:       e.TrustID,
+			ConsumerID:    e.ConsumerID,
+			AccessTokenID: e.AccessTokenID,
+			AuditID:       e.AuditID,
+			AuditChainID:  e.AuditChainID,
+			IssuedBefore:  *issuedBefore,
+			ExpiresAt:     expiresAt,
+			RevokedAt:     revokedAt,
+		})
+	}
+
+	return eventList, nil
+}
+
+// GetAllPolicies is used to retrieve list of policies.
+// Policies are known only to authentication API in v3, with v2 empty list is returned.
+func GetAllPolicies(ctx context.Context, provider *gophercloud.ProviderClient) ([]types.Policy, error) {
+	policyList := []types.Policy{}
+	if !strings.Contains(provider.IdentityEndpoint, "v3") {
+		return policyList, nil
+	}
+
+	client := openstack.NewIdentityV3(withContext(ctx, provider))
+
+	plcs, err := policies.List(client).Extract()
+	if err != nil {
+		return policyList, err
+	}
+
+	for _, p := range plcs {
+		policyList = append(policyList, types.Policy{ID: p.ID, Type: p.Type})
+	}
+
+	return policyList, nil
+}
+
+// GetAllRegisteredLimits is used to retrieve list of registered limits, i.e. default limits of resources.
+// Unified limits are known only to authentication API in v3, with v2 empty list is returned.
+func GetAllRegisteredLimits(ctx context.Context, provider *gophercloud.ProviderClient) ([]types.RegisteredLimit, error) {
+	registeredLimitList := []types.RegisteredLimit{}
+	if !strings.Contains(provider.IdentityEndpoint, "v3") {
+		return registeredLimitList, nil
+	}
+
+	client := openstack.NewIdentityV3(withContext(ctx, provider))
+
+	lmts, err := registeredlimits.List(client).Extract()
+	if err != nil {
+		return registeredLimitList, err
+	}
+
+	for _, l := range lmts {
+		registeredLimitList = append(registeredLimitList, types.RegisteredLimit{
+			ID:           l.ID,
+			ServiceID:    l.ServiceID,
+			RegionID:     l.RegionID,
+			ResourceName: l.ResourceName,
+			DefaultLimit: l.DefaultLimit,
+		})
+	}
+
+	return registeredLimitList, nil
+}
+
+// GetAllLimits is used to retrieve list of limits set for projects and domains.
+// Unified limits are known only to authentication API in v3, with v2 empty list is returned.
+func GetAllLimits(ctx context.Context, provider *gophercloud.ProviderClient) ([]types.Limit, error) {
+	limitList := []types.Limit{}
+	if !strings.Contains(provider.IdentityEndpoint, "v3") {
+		return limitList, nil
+	}
+
+	client := openstack.NewIdentityV3(withContext(ctx, provider))
+
+	lmts, err := limits.List(client).Extract()
+	if err != nil {
+		return limitList, err
+	}
+
+	for _, l := range lmts {
+		limitList = append(limitList, types.Limit{
+			ID:            l.ID,
+			ProjectID:     l.ProjectID,
+			DomainID:      l.DomainID,
+			ServiceID:     l.ServiceID,
+			RegionID:      l.RegionID,
+			ResourceName:  l.ResourceName,
+			ResourceLimit: l.ResourceLimit,
+		})
+	}
+
+	return limitList, nil
+}
+
+// GetApplicationCredentials is used to retrieve application credentials of given users.
+// Users are queried concurrently, at most concurrency requests are sent at the same time.
+// Application credentials are known only to authentication API in v3, with v2 empty list is returned.
+func GetApplicationCredentials(ctx context.Context, provider *gophercloud.ProviderClient, userList []types.User, concurrency int) ([]types.ApplicationCredential, error) {
+	appCredentialList := []types.ApplicationCredential{}
+	if !strings.Contains(provider.IdentityEndpoint, "v3") {
+		return appCredentialList, nil
+	}
+
+	client := openstack.NewIdentityV3(withContext(ctx, provider))
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var mutex sync.Mutex
+	var done sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	failed := []string{}
+
+	for _, usr := range userList {
+		if ctx.Err() != nil {
+			break
+		}
+
+		slots <- struct{}{}
+		done.Add(1)
+		go func(usr types.User) {
+			defer func() {
+				<-slots
+				done.Done()
+			}()
+
+			creds, err := appcredentials.List(client, usr.ID).Extract()
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", usr.Name, err))
+				return
+			}
+			for _, c := range creds {
+				expiresAt, err := parseTime(c.ExpiresAt)
+				if err != nil {
+					failed = append(failed, fmt.Sprintf("%s: cannot parse expires_at of %s: %v", usr.Name, c.Name, err))
+					continue
+				}
+				appCredentialList = append(appCredentialList, types.ApplicationCredential{
+					ID:        c.ID,
+					Name:      c.Name,
+					UserID:    usr.ID,
+					ProjectID: c.ProjectID,
+					ExpiresAt: expiresAt,
+				})
+			}
+		}(usr)
+	}
+	done.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return appCredentialList, err
+	}
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return appCredentialList, fmt.Errorf("cannot get application credentials of %d user(s): %s", len(failed), strings.Join(failed, "; "))
+	}
+
+	return appCredentialList, nil
+}
+
 // GetAllServices is used to retrieve list of available services for authenticated admin
-func GetAllServices(provider *gophercloud.ProviderClient) ([]types.Service, error) {
+func GetAllServices(ctx context.Context, provider *gophercloud.ProviderClient) ([]types.Service, error) {
 	serviceList := []types.Service{}
 
-	client := openstack.NewIdentityV3(provider)
+	client := openstack.NewIdentityV3(withContext(ctx, provider))
 
 	opts := services.ListOpts{}
 	pager := services.List(client, opts)
@@ -118,10 +461,10 @@ func GetAllServices(provider *gophercloud.ProviderClient) ([]types.Service, erro
 }
 
 // GetAllServices is used to retrieve list of available services for authenticated admin
-func GetAllEndpoints(provider *gophercloud.ProviderClient) ([]types.Endpoint, error) {
+func GetAllEndpoints(ctx context.Context, provider *gophercloud.ProviderClient) ([]types.Endpoint, error) {
 	endpointList := []types.Endpoint{}
 
-	client := openstack.NewIdentityV3(provider)
+	client := openstack.NewIdentityV3(withContext(ctx, provider))
 
 	opts := endpoints.ListOpts{}
 	pager := endpoints.List(client, opts)
@@ -137,36 +480,99 @@ func GetAllEndpoints(provider *gophercloud.ProviderClient) ([]types.Endpoint, er
 
 	for _, endpt := range endpts {
 		endpointList = append(endpointList, types.Endpoint{
-			ID:        endpt.ID,
-			ServiceID: endpt.ServiceID,
-			URL:       endpt.URL,
-			Region:    endpt.Region,
-			//Availability: endpt.Availability,
-			Name: endpt.Name,
+			ID:           endpt.ID,
+			ServiceID:    endpt.ServiceID,
+			URL:          endpt.URL,
+			Region:       endpt.Region,
+			Availability: string(endpt.Availability),
+			Name:         endpt.Name,
 		})
 	}
 
 	return endpointList, nil
 }
 
-func GetUsersPerTenant(provider *gophercloud.ProviderClient, tenantList []types.Tenant) (map[string]int, error) {
+// GetUsersPerTenant is used to retrieve number of users for each of given tenants, keyed by tenant ID
+// as tenant names are unique only within a domain.
+// Tenants which could not be queried are left out of returned map and reported in returned error.
+// At most concurrency tenants are queried at the same time.
+func GetUsersPerTenant(ctx context.Context, provider *gophercloud.ProviderClient, tenantList []types.Tenant, concurrency int) (map[string]int, error) {
 	tenantUsersCount := map[string]int{}
 	var client *gophercloud.ServiceClient
 
 	if strings.Contains(provider.IdentityEndpoint, "v3") {
-		client = openstack.NewIdentityV3(provider)
+		client = openstack.NewIdentityV3(withContext(ctx, provider))
 	} else {
-		client = openstack.NewIdentityV2(provider)
+		client = openstack.NewIdentityV2(withContext(ctx, provider))
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
 	}
 
+	var mutex sync.Mutex
+	var done sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	failed := []string{}
+
 	for _, tnt := range tenantList {
-		usrs, err := tenantusers.Get(client, tnt.ID).Extract()
-		if err != nil {
-			return tenantUsersCount, err
+		if ctx.Err() != nil {
+			break
 		}
 
-		tenantUsersCount[tnt.Name] = len(usrs)
+		slots <- struct{}{}
+		done.Add(1)
+		go func(tnt types.Tenant) {
+			defer func() {
+				<-slots
+				done.Done()
+			}()
+
+			usrs, err := tenantusers.Get(client, tnt.ID).Extract()
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", tnt.Name, err))
+				return
+			}
+			tenantUsersCount[tnt.ID] = len(usrs)
+		}(tnt)
+	}
+	done.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return tenantUsersCount, err
+	}
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return tenantUsersCount, fmt.Errorf("cannot get users of %d tenant(s): %s", len(failed), strings.Join(failed, "; "))
 	}
 
 	return tenantUsersCount, nil
 }
+
+// timeLayouts lists formats of timestamps returned by Keystone
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.000000",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseTime parses timestamp returned by Keystone, timestamps without zone are in UTC.
+// It returns nil for empty value.
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown time format %q", value)
+}
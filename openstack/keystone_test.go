@@ -17,9 +17,11 @@ limitations under the License.
 package openstack
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	th "github.com/rackspace/gophercloud/testhelper"
 	. "github.com/smartystreets/goconvey/convey"
@@ -42,6 +44,15 @@ func (s *KeystoneSuite) SetupSuite() {
 	registerServices(s)
 	registerEndpoints(s)
 	registerTenantUsers(s)
+	registerBrokenTenantUsers(s)
+	registerProjects(s)
+	registerDomains(s)
+	registerCredentials(s)
+	registerApplicationCredentials(s)
+	registerTrusts(s)
+	registerRevocationEvents(s)
+	registerPolicies(s)
+	registerLimits(s)
 }
 
 func (suite *KeystoneSuite) TearDownSuite() {
@@ -57,13 +68,13 @@ func (s *KeystoneSuite) TestGetAllTenants() {
 	Convey("Given list of OpenStack tenants is requested", s.T(), func() {
 
 		Convey("When authentication is required", func() {
-			provider, err := Authenticate(th.Endpoint(), "me", "secret", "tenant", "", "")
+			provider, err := Authenticate(context.Background(), th.Endpoint(), "me", "secret", "tenant", "", "", "")
 			th.AssertNoErr(s.T(), err)
 			th.CheckEquals(s.T(), s.Token, provider.TokenID)
 
 			Convey("and GetAllTenants called", func() {
 
-				tenantList, err := GetAllTenants(provider)
+				tenantList, err := GetAllTenants(context.Background(), provider)
 
 				Convey("Then number of tenants is returned", func() {
 					So(len(tenantList), ShouldEqual, 2)
@@ -77,22 +88,64 @@ func (s *KeystoneSuite) TestGetAllTenants() {
 	})
 }
 
+func (s *KeystoneSuite) TestGetAllProjects() {
+	Convey("Given list of OpenStack tenants is requested with authentication API in v3", s.T(), func() {
+
+		Convey("When authentication is required", func() {
+			provider, err := Authenticate(context.Background(), th.Endpoint(), "me", "secret", "tenant", "", "", "")
+			th.AssertNoErr(s.T(), err)
+			provider.IdentityEndpoint = th.Endpoint() + "v3/"
+
+			Convey("and GetAllTenants called", func() {
+
+				tenantList, err := GetAllTenants(context.Background(), provider)
+
+				Convey("Then projects are returned with their domain and parent", func() {
+					So(err, ShouldBeNil)
+					So(len(tenantList), ShouldEqual, 2)
+					So(tenantList[1], ShouldResemble, types.Tenant{
+						Name:       "demo",
+						ID:         "p222",
+						DomainID:   "default",
+						DomainName: "Default",
+						ParentID:   "p111",
+						Tags:       []string{"env:prod", "cost-center:42"},
+					})
+					So(tenantList[0].Tags, ShouldBeEmpty)
+				})
+			})
+		})
+	})
+}
+
 func (s *KeystoneSuite) TestGetAllUsers() {
 	Convey("Given list of OpenStack users is requested", s.T(), func() {
 
 		Convey("When authentication is required", func() {
-			provider, err := Authenticate(th.Endpoint(), "me", "secret", "tenant", "", "")
+			provider, err := Authenticate(context.Background(), th.Endpoint(), "me", "secret", "tenant", "", "", "")
 			th.AssertNoErr(s.T(), err)
 			th.CheckEquals(s.T(), s.Token, provider.TokenID)
 
 			Convey("and GetAllUsers called", func() {
 
-				userList, err := GetAllUsers(provider)
+				userList, err := GetAllUsers(context.Background(), provider)
 
 				Convey("Then number of users is returned", func() {
 					So(len(userList), ShouldEqual, 3)
 				})
 
+				Convey("and last activity is decoded when recorded", func() {
+					So(userList[0].LastActiveAt, ShouldNotBeNil)
+					So(userList[0].LastActiveAt.Equal(time.Date(2016, 11, 3, 10, 21, 43, 0, time.UTC)), ShouldBeTrue)
+					So(userList[1].LastActiveAt, ShouldBeNil)
+				})
+
+				Convey("and password expiry is decoded when set", func() {
+					So(userList[0].PasswordExpiresAt, ShouldNotBeNil)
+					So(userList[0].PasswordExpiresAt.Equal(time.Date(2017, 2, 1, 10, 21, 43, 0, time.UTC)), ShouldBeTrue)
+					So(userList[1].PasswordExpiresAt, ShouldBeNil)
+				})
+
 				Convey("and no error reported", func() {
 					So(err, ShouldBeNil)
 				})
@@ -105,13 +158,13 @@ func (s *KeystoneSuite) TestGetAllServices() {
 	Convey("Given list of OpenStack services is requested", s.T(), func() {
 
 		Convey("When authentication is required", func() {
-			provider, err := Authenticate(th.Endpoint(), "me", "secret", "tenant", "", "")
+			provider, err := Authenticate(context.Background(), th.Endpoint(), "me", "secret", "tenant", "", "", "")
 			th.AssertNoErr(s.T(), err)
 			th.CheckEquals(s.T(), s.Token, provider.TokenID)
 
 			Convey("and GetAllServices called", func() {
 
-				serviceList, err := GetAllServices(provider)
+				serviceList, err := GetAllServices(context.Background(), provider)
 
 				Convey("Then number of services is returned", func() {
 					So(len(serviceList), ShouldEqual, 4)
@@ -129,18 +182,23 @@ func (s *KeystoneSuite) TestGetAllEndpoints() {
 	Convey("Given list of OpenStack endpoints is requested", s.T(), func() {
 
 		Convey("When authentication is required", func() {
-			provider, err := Authenticate(th.Endpoint(), "me", "secret", "tenant", "", "")
+			provider, err := Authenticate(context.Background(), th.Endpoint(), "me", "secret", "tenant", "", "", "")
 			th.AssertNoErr(s.T(), err)
 			th.CheckEquals(s.T(), s.Token, provider.TokenID)
 
 			Convey("and GetAllEndpoints called", func() {
 
-				endpointList, err := GetAllEndpoints(provider)
+				endpointList, err := GetAllEndpoints(context.Background(), provider)
 
 				Convey("Then number of endpoints is returned", func() {
 					So(len(endpointList), ShouldEqual, 4)
 				})
 
+				Convey("and interface of endpoints is known", func() {
+					So(endpointList[0].Availability, ShouldEqual, "public")
+					So(endpointList[2].Availability, ShouldEqual, "admin")
+				})
+
 				Convey("and no error reported", func() {
 					So(err, ShouldBeNil)
 				})
@@ -153,17 +211,17 @@ func (s *KeystoneSuite) TestGetTenantUsers() {
 	Convey("Given list of OpenStack users for particular tenant is requested", s.T(), func() {
 
 		Convey("When authentication is required", func() {
-			provider, err := Authenticate(th.Endpoint(), "me", "secret", "tenant", "", "")
+			provider, err := Authenticate(context.Background(), th.Endpoint(), "me", "secret", "tenant", "", "", "")
 			th.AssertNoErr(s.T(), err)
 			th.CheckEquals(s.T(), s.Token, provider.TokenID)
 
 			Convey("and GetUsersPerTenant called", func() {
 				tenants := []types.Tenant{types.Tenant{ID: "11111", Name: "demo"}}
-				tenantUsers, err := GetUsersPerTenant(provider, tenants)
+				tenantUsers, err := GetUsersPerTenant(context.Background(), provider, tenants, 1)
 
 				Convey("Then number of users for tenants is returned", func() {
 					So(len(tenantUsers), ShouldEqual, 1)
-					val, ok := tenantUsers["demo"]
+					val, ok := tenantUsers["11111"]
 					So(ok, ShouldBeTrue)
 					So(val, ShouldEqual, 3)
 				})
@@ -176,6 +234,283 @@ func (s *KeystoneSuite) TestGetTenantUsers() {
 	})
 }
 
+func (s *KeystoneSuite) TestGetTenantUsersPartially() {
+	Convey("Given users of tenant which cannot be listed are requested", s.T(), func() {
+
+		Convey("When authentication is required", func() {
+			provider, err := Authenticate(context.Background(), th.Endpoint(), "me", "secret", "tenant", "", "", "")
+			th.AssertNoErr(s.T(), err)
+
+			Convey("and GetUsersPerTenant called", func() {
+				tenants := []types.Tenant{
+					types.Tenant{ID: "11111", Name: "demo"},
+					types.Tenant{ID: "33333", Name: "broken"},
+				}
+				tenantUsers, err := GetUsersPerTenant(context.Background(), provider, tenants, 2)
+
+				Convey("Then number of users is returned for tenants which could be listed", func() {
+					So(len(tenantUsers), ShouldEqual, 1)
+					So(tenantUsers["11111"], ShouldEqual, 3)
+				})
+
+				Convey("and failed tenant is reported in error", func() {
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldContainSubstring, "broken")
+				})
+			})
+		})
+	})
+}
+
+func (s *KeystoneSuite) TestGetAllCredentials() {
+	Convey("Given list of credentials is requested with authentication API in v3", s.T(), func() {
+
+		Convey("When authentication is required", func() {
+			provider, err := Authenticate(context.Background(), th.Endpoint(), "me", "secret", "tenant", "", "", "")
+			th.AssertNoErr(s.T(), err)
+			provider.IdentityEndpoint = th.Endpoint() + "v3/"
+
+			Convey("and GetAllCredentials called", func() {
+
+				credentialList, err := GetAllCredentials(context.Background(), provider)
+
+				Convey("Then credentials are returned with their type and project", func() {
+					So(err, ShouldBeNil)
+					So(len(credentialList), ShouldEqual, 2)
+					So(credentialList[0], ShouldResemble, types.Credential{
+						ID:        "c111",
+						Type:      "ec2",
+						UserID:    "u111",
+						ProjectID: "p222",
+					})
+					So(credentialList[1].ProjectID, ShouldBeEmpty)
+				})
+			})
+		})
+	})
+
+	Convey("Given list of credentials is requested with authentication API in v2", s.T(), func() {
+		provider, err := Authenticate(context.Background(), th.Endpoint(), "me", "secret", "tenant", "", "", "")
+		th.AssertNoErr(s.T(), err)
+
+		credentialList, err := GetAllCredentials(context.Background(), provider)
+		So(err, ShouldBeNil)
+		So(credentialList, ShouldBeEmpty)
+	})
+}
+
+func (s *KeystoneSuite) TestGetAllTrusts() {
+	Convey("Given list of trusts is requested with authentication API in v3", s.T(), func() {
+
+		Convey("When authentication is required", func() {
+			provider, err := Authenticate(context.Background(), th.Endpoint(), "me", "secret", "tenant", "", "", "")
+			th.AssertNoErr(s.T(), err)
+			provider.IdentityEndpoint = th.Endpoint() + "v3/"
+
+			Convey("and GetAllTrusts called", func() {
+
+				trustList, err := GetAllTrusts(context.Background(), provider)
+
+				Convey("Then trusts are returned with their expiry and impersonation", func() {
+					So(err, ShouldBeNil)
+					So(len(trustList), ShouldEqual, 2)
+					So(trustList[0].TrustorUserID, ShouldEqual, "u111")
+					So(trustList[0].TrusteeUserID, ShouldEqual, "u222")
+					So(trustList[0].Impersonation, ShouldBeTrue)
+					So(trustList[0].ExpiresAt.Equal(time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)), ShouldBeTrue)
+					So(trustList[1].Impersonation, ShouldBeFalse)
+					So(trustList[1].ExpiresAt, ShouldBeNil)
+				})
+			})
+		})
+	})
+}
+
+func (s *KeystoneSuite) TestGetAllRevocationEvents() {
+	Convey("Given list of revocation events is requested with authentication API in v3", s.T(), func() {
+		provider, err := Authenticate(context.Background(), th.Endpoint(), "me", "secret", "tenant", "", "", "")
+		th.AssertNoErr(s.T(), err)
+		provider.IdentityEndpoint = th.Endpoint() + "v3/"
+
+		Convey("When GetAllRevocationEvents called", func() {
+			eventList, err := GetAllRevocationEvents(context.Background(), provider)
+
+			Convey("Then events are returned with their times", func() {
+				So(err, ShouldBeNil)
+				So(len(eventList), ShouldEqual, 2)
+				So(eventList[0].UserID, ShouldEqual, "u111")
+				So(eventList[0].IssuedBefore.Equal(time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)), ShouldBeTrue)
+				So(eventList[0].RevokedAt.Equal(time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)), ShouldBeTrue)
+				So(eventList[1].AuditID, ShouldEqual, "VcxU2JYqT8OzfUVvrjEITQ")
+				So(eventList[1].TrustID, ShouldEqual, "t111")
+				So(eventList[1].RevokedAt, ShouldBeNil)
+			})
+		})
+	})
+
+	Convey("Given list of revocation events is requested with authentication API in v2", s.T(), func() {
+		provider, err := Authenticate(context.Background(), th.Endpoint(), "me", "secret", "tenant", "", "", "")
+		th.AssertNoErr(s.T(), err)
+
+		eventList, err := GetAllRevocationEvents(context.Background(), provider)
+		So(err, ShouldBeNil)
+		So(eventList, ShouldBeEmpty)
+	})
+}
+
+func (s *KeystoneSuite) TestGetAllPolicies() {
+	Convey("Given list of policies is requested with authentication API in v3", s.T(), func() {
+		provider, err := Authenticate(context.Background(), th.Endpoint(), "me", "secret", "tenant", "", "", "")
+		th.AssertNoErr(s.T(), err)
+		provider.IdentityEndpoint = th.Endpoint() + "v3/"
+
+		policyList, err := GetAllPolicies(context.Background(), provider)
+		So(err, ShouldBeNil)
+		So(policyList, ShouldResemble, []types.Policy{types.Policy{ID: "pol111", Type: "application/json"}})
+	})
+}
+
+func (s *KeystoneSuite) TestGetAllLimits() {
+	Convey("Given unified limits are requested with authentication API in v3", s.T(), func() {
+
+		Convey("When authentication is required", func() {
+			provider, err := Authenticate(context.Background(), th.Endpoint(), "me", "secret", "tenant", "", "", "")
+			th.AssertNoErr(s.T(), err)
+			provider.IdentityEndpoint = th.Endpoint() + "v3/"
+
+			Convey("and GetAllRegisteredLimits and GetAllLimits called", func() {
+				registeredLimitList, err := GetAllRegisteredLimits(context.Background(), provider)
+				So(err, ShouldBeNil)
+				limitList, err := GetAllLimits(context.Background(), provider)
+				So(err, ShouldBeNil)
+
+				Convey("Then registered limits are returned with their defaults", func() {
+					So(registeredLimitList, ShouldResemble, []types.RegisteredLimit{
+						types.RegisteredLimit{
+							ID:           "rl111",
+							ServiceID:    "s111",
+							RegionID:     "RegionOne",
+							ResourceName: "volume",
+							DefaultLimit: 10,
+						},
+					})
+				})
+
+				Convey("and project limits are returned with their values", func() {
+					So(limitList, ShouldResemble, []types.Limit{
+						types.Limit{
+							ID:            "l111",
+							ProjectID:     "p222",
+							ServiceID:     "s111",
+							RegionID:      "RegionOne",
+							ResourceName:  "volume",
+							ResourceLimit: 20,
+						},
+					})
+				})
+			})
+		})
+	})
+}
+
+func (s *KeystoneSuite) TestGetApplicationCredentials() {
+	Convey("Given application credentials of users are requested", s.T(), func() {
+
+		Convey("When authentication is required", func() {
+			provider, err := Authenticate(context.Background(), th.Endpoint(), "me", "secret", "tenant", "", "", "")
+			th.AssertNoErr(s.T(), err)
+			provider.IdentityEndpoint = th.Endpoint() + "v3/"
+
+			Convey("and GetApplicationCredentials called", func() {
+				users := []types.User{
+					types.User{ID: "u111", Name: "demo"},
+					types.User{ID: "u999", Name: "broken"},
+				}
+				appCredentialList, err := GetApplicationCredentials(context.Background(), provider, users, 2)
+
+				Convey("Then application credentials are returned for users which could be listed", func() {
+					So(len(appCredentialList), ShouldEqual, 2)
+					for _, c := range appCredentialList {
+						So(c.UserID, ShouldEqual, "u111")
+						if c.ID == "a111" {
+							So(c.ExpiresAt.Equal(time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)), ShouldBeTrue)
+						} else {
+							So(c.ExpiresAt, ShouldBeNil)
+						}
+					}
+				})
+
+				Convey("and failed user is reported in error", func() {
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldContainSubstring, "broken")
+				})
+			})
+		})
+	})
+}
+
+func TestTokenExpiry(t *testing.T) {
+	Convey("Given Keystone whose token expires during collection", t, func() {
+		keystone := newExpiringKeystone()
+		defer keystone.Close()
+
+		provider, err := Authenticate(context.Background(), keystone.URL+"/", "me", "secret", "tenant", "", "", "")
+		So(err, ShouldBeNil)
+		keystone.expire()
+
+		Convey("When users of tenants are requested concurrently", func() {
+			tenants := []types.Tenant{}
+			for i := 0; i < 8; i++ {
+				tenants = append(tenants, types.Tenant{ID: fmt.Sprintf("t%d", i), Name: fmt.Sprintf("tenant%d", i)})
+			}
+			tenantUsers, err := GetUsersPerTenant(context.Background(), provider, tenants, 8)
+
+			Convey("Then users of every tenant are returned after single re-authentication", func() {
+				So(err, ShouldBeNil)
+				So(len(tenantUsers), ShouldEqual, 8)
+				So(keystone.authentications(), ShouldEqual, 2)
+			})
+		})
+
+		Convey("When application credentials of users are requested concurrently", func() {
+			provider.IdentityEndpoint = keystone.URL + "/v3/"
+			users := []types.User{}
+			for i := 0; i < 8; i++ {
+				users = append(users, types.User{ID: fmt.Sprintf("u%d", i), Name: fmt.Sprintf("user%d", i)})
+			}
+			appCredentialList, err := GetApplicationCredentials(context.Background(), provider, users, 8)
+
+			Convey("Then credentials of every user are returned after single re-authentication", func() {
+				So(err, ShouldBeNil)
+				So(len(appCredentialList), ShouldEqual, 8)
+				So(keystone.authentications(), ShouldEqual, 2)
+			})
+		})
+	})
+}
+
+func (s *KeystoneSuite) TestRateLimiter() {
+	Convey("Given rate limiter allowing 10 requests per second", s.T(), func() {
+		limiter := NewRateLimiter(10, 1)
+
+		Convey("When requests are sent by rate limited provider", func() {
+			provider, err := Authenticate(context.Background(), th.Endpoint(), "me", "secret", "tenant", "", "", "", WithRateLimiter(limiter))
+			th.AssertNoErr(s.T(), err)
+
+			_, err = GetAllTenants(context.Background(), provider)
+			th.AssertNoErr(s.T(), err)
+			_, err = GetAllUsers(context.Background(), provider)
+			th.AssertNoErr(s.T(), err)
+
+			Convey("Then time spent waiting for the limiter is reported", func() {
+				// authentication takes two requests (version discovery and token),
+				// so three out of four requests have to wait for a token
+				So(limiter.Waited(), ShouldBeGreaterThanOrEqualTo, 250*time.Millisecond)
+			})
+		})
+	})
+}
+
 func registerRoot() {
 	th.Mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, `
@@ -278,7 +613,9 @@ func registerUsers(s *KeystoneSuite) {
 						"email": "heat@localhost",
 						"enabled": true,
 						"id": "27b6b98022314a6b9c4524efaedf4694",
+						"last_active_at": "2016-11-03T10:21:43.000000",
 						"name": "heat",
+						"password_expires_at": "2017-02-01T10:21:43Z",
 						"username": "heat"
 					},
 					{
@@ -463,3 +800,307 @@ func registerTenantUsers(s *KeystoneSuite) {
 	`)
 	})
 }
+
+func registerBrokenTenantUsers(s *KeystoneSuite) {
+	th.Mux.HandleFunc("/v2.0/tenants/33333/users", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+		th.TestHeader(s.T(), r, "X-Auth-Token", s.Token)
+
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+}
+
+func registerProjects(s *KeystoneSuite) {
+	th.Mux.HandleFunc("/v3/projects", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+		th.TestHeader(s.T(), r, "X-Auth-Token", s.Token)
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `
+			{
+				"projects": [
+					{
+						"domain_id": "default",
+						"enabled": true,
+						"id": "p111",
+						"is_domain": false,
+						"name": "admin",
+						"parent_id": "default"
+					},
+					{
+						"domain_id": "default",
+						"enabled": true,
+						"id": "p222",
+						"is_domain": false,
+						"name": "demo",
+						"parent_id": "p111",
+						"tags": ["env:prod", "cost-center:42"]
+					}
+				],
+				"links": {
+					"next": null,
+					"previous": null,
+					"self": "http://keystone:5000/v3/projects"
+				}
+			}
+		`)
+	})
+}
+
+func registerDomains(s *KeystoneSuite) {
+	th.Mux.HandleFunc("/v3/domains", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+		th.TestHeader(s.T(), r, "X-Auth-Token", s.Token)
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `
+			{
+				"domains": [
+					{
+						"description": "The default domain",
+						"enabled": true,
+						"id": "default",
+						"name": "Default"
+					}
+				],
+				"links": {
+					"next": null,
+					"previous": null,
+					"self": "http://keystone:5000/v3/domains"
+				}
+			}
+		`)
+	})
+}
+
+func registerCredentials(s *KeystoneSuite) {
+	th.Mux.HandleFunc("/v3/credentials", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+		th.TestHeader(s.T(), r, "X-Auth-Token", s.Token)
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `
+			{
+				"credentials": [
+					{
+						"blob": "{\"access\":\"181920\",\"secret\":\"secretKey\"}",
+						"id": "c111",
+						"project_id": "p222",
+						"type": "ec2",
+						"user_id": "u111"
+					},
+					{
+						"blob": "-----BEGIN CERTIFICATE-----",
+						"id": "c222",
+						"project_id": null,
+						"type": "cert",
+						"user_id": "u111"
+					}
+				],
+				"links": {
+					"next": null,
+					"previous": null,
+					"self": "http://keystone:5000/v3/credentials"
+				}
+			}
+		`)
+	})
+}
+
+func registerApplicationCredentials(s *KeystoneSuite) {
+	th.Mux.HandleFunc("/v3/users/u111/application_credentials", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+		th.TestHeader(s.T(), r, "X-Auth-Token", s.Token)
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `
+			{
+				"application_credentials": [
+					{
+						"description": "Backups",
+						"expires_at": "2017-03-01T00:00:00.000000",
+						"id": "a111",
+						"name": "backup",
+						"project_id": "p222",
+						"unrestricted": false
+					},
+					{
+						"description": null,
+						"expires_at": null,
+						"id": "a222",
+						"name": "monitoring",
+						"project_id": "p222",
+						"unrestricted": false
+					}
+				],
+				"links": {
+					"next": null,
+					"previous": null,
+					"self": "http://keystone:5000/v3/users/u111/application_credentials"
+				}
+			}
+		`)
+	})
+	th.Mux.HandleFunc("/v3/users/u999/application_credentials", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+}
+
+func registerTrusts(s *KeystoneSuite) {
+	th.Mux.HandleFunc("/v3/OS-TRUST/trusts", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+		th.TestHeader(s.T(), r, "X-Auth-Token", s.Token)
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `
+			{
+				"trusts": [
+					{
+						"expires_at": "2017-03-01T12:00:00.000000Z",
+						"id": "t111",
+						"impersonation": true,
+						"project_id": "p222",
+						"remaining_uses": null,
+						"trustee_user_id": "u222",
+						"trustor_user_id": "u111"
+					},
+					{
+						"expires_at": null,
+						"id": "t222",
+						"impersonation": false,
+						"project_id": "p222",
+						"remaining_uses": 5,
+						"trustee_user_id": "u333",
+						"trustor_user_id": "u111"
+					}
+				],
+				"links": {
+					"next": null,
+					"previous": null,
+					"self": "http://keystone:5000/v3/OS-TRUST/trusts"
+				}
+			}
+		`)
+	})
+}
+
+func registerRevocationEvents(s *KeystoneSuite) {
+	th.Mux.HandleFunc("/v3/OS-REVOKE/events", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+		th.TestHeader(s.T(), r, "X-Auth-Token", s.Token)
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `
+			{
+				"events": [
+					{
+						"issued_before": "2017-03-01T12:00:00.000000Z",
+						"revoked_at": "2017-03-01T12:00:00.000000Z",
+						"user_id": "u111"
+					},
+					{
+						"audit_id": "VcxU2JYqT8OzfUVvrjEITQ",
+						"issued_before": "2017-03-02T08:30:00.000000Z",
+						"OS-TRUST:trust_id": "t111"
+					}
+				],
+				"links": {
+					"next": null,
+					"previous": null,
+					"self": "http://keystone:5000/v3/OS-REVOKE/events"
+				}
+			}
+		`)
+	})
+}
+
+func registerPolicies(s *KeystoneSuite) {
+	th.Mux.HandleFunc("/v3/policies", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+		th.TestHeader(s.T(), r, "X-Auth-Token", s.Token)
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `
+			{
+				"policies": [
+					{
+						"blob": {
+							"foobar_user": ["role:compute-user"]
+						},
+						"id": "pol111",
+						"type": "application/json"
+					}
+				],
+				"links": {
+					"next": null,
+					"previous": null,
+					"self": "http://keystone:5000/v3/policies"
+				}
+			}
+		`)
+	})
+}
+
+func registerLimits(s *KeystoneSuite) {
+	th.Mux.HandleFunc("/v3/registered_limits", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+		th.TestHeader(s.T(), r, "X-Auth-Token", s.Token)
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `
+			{
+				"registered_limits": [
+					{
+						"default_limit": 10,
+						"description": "Number of volumes",
+						"id": "rl111",
+						"region_id": "RegionOne",
+						"resource_name": "volume",
+						"service_id": "s111"
+					}
+				],
+				"links": {
+					"next": null,
+					"previous": null,
+					"self": "http://keystone:5000/v3/registered_limits"
+				}
+			}
+		`)
+	})
+	th.Mux.HandleFunc("/v3/limits", func(w http.ResponseWriter, r *http.Request) {
+		th.TestMethod(s.T(), r, "GET")
+		th.TestHeader(s.T(), r, "X-Auth-Token", s.Token)
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `
+			{
+				"limits": [
+					{
+						"description": null,
+						"domain_id": null,
+						"id": "l111",
+						"project_id": "p222",
+						"region_id": "RegionOne",
+						"resource_limit": 20,
+						"resource_name": "volume",
+						"service_id": "s111"
+					}
+				],
+				"links": {
+					"next": null,
+					"previous": null,
+					"self": "http://keystone:5000/v3/limits"
+				}
+			}
+		`)
+	})
+}
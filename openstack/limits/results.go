@@ -0,0 +1,50 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package limits
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/rackspace/gophercloud"
+)
+
+// Limit represents Keystone v3 limit of resource of a service for single project or domain
+type Limit struct {
+	ID            string `json:"id" mapstructure:"id"`
+	ProjectID     string `json:"project_id" mapstructure:"project_id"`
+	DomainID      string `json:"domain_id" mapstructure:"domain_id"`
+	ServiceID     string `json:"service_id" mapstructure:"service_id"`
+	RegionID      string `json:"region_id" mapstructure:"region_id"`
+	ResourceName  string `json:"resource_name" mapstructure:"resource_name"`
+	ResourceLimit int    `json:"resource_limit" mapstructure:"resource_limit"`
+}
+
+// ListResult represents the result of a list operation.
+type ListResult struct {
+	gophercloud.Result
+}
+
+// Extract will get list of limits out of the ListResult object.
+func (r ListResult) Extract() ([]Limit, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+
+	var resp struct {
+		Limits []Limit `json:"limits" mapstructure:"limits"`
+	}
+
+	err := mapstructure.Decode(r.Body, &resp)
+
+	return resp.Limits, err
+}
@@ -0,0 +1,45 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package policies
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/rackspace/gophercloud"
+)
+
+// Policy represents Keystone v3 policy, its blob is not decoded
+type Policy struct {
+	ID   string `json:"id" mapstructure:"id"`
+	Type string `json:"type" mapstructure:"type"`
+}
+
+// ListResult represents the result of a list operation.
+type ListResult struct {
+	gophercloud.Result
+}
+
+// Extract will get list of policies out of the ListResult object.
+func (r ListResult) Extract() ([]Policy, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+
+	var resp struct {
+		Policies []Policy `json:"policies" mapstructure:"policies"`
+	}
+
+	err := mapstructure.Decode(r.Body, &resp)
+
+	return resp.Policies, err
+}
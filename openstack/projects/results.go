@@ -0,0 +1,50 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package projects
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/rackspace/gophercloud"
+)
+
+// Project represents Keystone v3 project
+type Project struct {
+	ID       string `json:"id" mapstructure:"id"`
+	Name     string `json:"name" mapstructure:"name"`
+	DomainID string `json:"domain_id" mapstructure:"domain_id"`
+	ParentID string `json:"parent_id" mapstructure:"parent_id"`
+	Enabled  bool   `json:"enabled" mapstructure:"enabled"`
+	// Tags are set on projects since Keystone Queens
+	Tags []string `json:"tags" mapstructure:"tags"`
+}
+
+// ListResult represents the result of a list operation.
+type ListResult struct {
+	gophercloud.Result
+}
+
+// Extract will get list of projects out of the ListResult object.
+func (r ListResult) Extract() ([]Project, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+
+	var resp struct {
+		Projects []Project `json:"projects" mapstructure:"projects"`
+	}
+
+	err := mapstructure.Decode(r.Body, &resp)
+
+	return resp.Projects, err
+}
@@ -0,0 +1,80 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimiter is a token bucket limiting rate of requests sent to Keystone API.
+// Single limiter is meant to be shared by all requests made on behalf of the plugin.
+type RateLimiter struct {
+	limiter *rate.Limiter
+
+	mutex  sync.Mutex
+	waited time.Duration
+}
+
+// NewRateLimiter creates token bucket which allows requestsPerSecond requests on average
+// and bursts of at most burst requests
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		limiter: rate.NewLimiter(rate.Limit(requestsPerSecond), burst),
+	}
+}
+
+// Wait blocks until next request is allowed to be sent or ctx is done
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	start := time.Now()
+	err := r.limiter.Wait(ctx)
+
+	r.mutex.Lock()
+	r.waited += time.Since(start)
+	r.mutex.Unlock()
+
+	return err
+}
+
+// Waited returns total time requests spent waiting for the limiter
+func (r *RateLimiter) Waited() time.Duration {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.waited
+}
+
+// limitedTransport delays requests until they are allowed by rate limiter
+type limitedTransport struct {
+	limiter *RateLimiter
+	next    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,48 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package registeredlimits
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/rackspace/gophercloud"
+)
+
+// RegisteredLimit represents Keystone v3 registered limit, the default limit of resource of a service
+type RegisteredLimit struct {
+	ID           string `json:"id" mapstructure:"id"`
+	ServiceID    string `json:"service_id" mapstructure:"service_id"`
+	RegionID     string `json:"region_id" mapstructure:"region_id"`
+	ResourceName string `json:"resource_name" mapstructure:"resource_name"`
+	DefaultLimit int    `json:"default_limit" mapstructure:"default_limit"`
+}
+
+// ListResult represents the result of a list operation.
+type ListResult struct {
+	gophercloud.Result
+}
+
+// Extract will get list of registered limits out of the ListResult object.
+func (r ListResult) Extract() ([]RegisteredLimit, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+
+	var resp struct {
+		RegisteredLimits []RegisteredLimit `json:"registered_limits" mapstructure:"registered_limits"`
+	}
+
+	err := mapstructure.Decode(r.Body, &resp)
+
+	return resp.RegisteredLimits, err
+}
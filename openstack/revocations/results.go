@@ -0,0 +1,57 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package revocations
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/rackspace/gophercloud"
+)
+
+// Event represents Keystone v3 OS-REVOKE revocation event, tokens matching all of its set fields
+// and issued before IssuedBefore are revoked
+type Event struct {
+	UserID        string `json:"user_id" mapstructure:"user_id"`
+	ProjectID     string `json:"project_id" mapstructure:"project_id"`
+	DomainID      string `json:"domain_id" mapstructure:"domain_id"`
+	RoleID        string `json:"role_id" mapstructure:"role_id"`
+	TrustID       string `json:"OS-TRUST:trust_id" mapstructure:"OS-TRUST:trust_id"`
+	ConsumerID    string `json:"OS-OAUTH1:consumer_id" mapstructure:"OS-OAUTH1:consumer_id"`
+	AccessTokenID string `json:"OS-OAUTH1:access_token_id" mapstructure:"OS-OAUTH1:access_token_id"`
+	AuditID       string `json:"audit_id" mapstructure:"audit_id"`
+	AuditChainID  string `json:"audit_chain_id" mapstructure:"audit_chain_id"`
+	IssuedBefore  string `json:"issued_before" mapstructure:"issued_before"`
+	ExpiresAt     string `json:"expires_at" mapstructure:"expires_at"`
+	// RevokedAt is empty with Keystone releases which do not report it
+	RevokedAt string `json:"revoked_at" mapstructure:"revoked_at"`
+}
+
+// ListResult represents the result of a list operation.
+type ListResult struct {
+	gophercloud.Result
+}
+
+// Extract will get list of revocation events out of the ListResult object.
+func (r ListResult) Extract() ([]Event, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+
+	var resp struct {
+		Events []Event `json:"events" mapstructure:"events"`
+	}
+
+	err := mapstructure.Decode(r.Body, &resp)
+
+	return resp.Events, err
+}
@@ -0,0 +1,101 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// authHeader is the header carrying token of authenticated requests
+const authHeader = "X-Auth-Token"
+
+// session keeps the current token of provider client, shared by copies of the client made for every call.
+// Token is read and replaced under mutex; re-authentication is single-flight, callers whose token
+// has been replaced since it was rejected use the new token instead of authenticating again.
+type session struct {
+	mutex        sync.Mutex
+	token        string
+	authenticate func(ctx context.Context) (string, error)
+}
+
+// tokenID returns the current token
+func (s *session) tokenID() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.token
+}
+
+// refresh re-authenticates when rejected token is still the current one and returns the current token
+func (s *session) refresh(ctx context.Context, rejected string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.token != rejected {
+		return s.token, nil
+	}
+
+	token, err := s.authenticate(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.token = token
+	return token, nil
+}
+
+// sessionTransport sends authenticated requests with the current token of session.
+// Request rejected with 401 is sent once more after re-authentication, unless it has a body which cannot be resent.
+type sessionTransport struct {
+	session *session
+	next    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *sessionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// requests without token, e.g. authentication itself, are sent as they are
+	if req.Header.Get(authHeader) == "" {
+		return t.next.RoundTrip(req)
+	}
+
+	token := t.session.tokenID()
+	resp, err := t.next.RoundTrip(withToken(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || req.Body != nil {
+		return resp, err
+	}
+
+	// response is discarded, so connection can be reused
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	token, err = t.session.refresh(req.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(withToken(req, token))
+}
+
+// withToken returns copy of request with given token, as transport must not modify the request
+func withToken(req *http.Request, token string) *http.Request {
+	r := new(http.Request)
+	*r = *req
+	r.Header = http.Header{}
+	for key, values := range req.Header {
+		r.Header[key] = values
+	}
+	r.Header.Set(authHeader, token)
+	return r
+}
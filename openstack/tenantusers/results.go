@@ -34,6 +34,9 @@ type GetResult struct {
 
 // Extract will get the Volume object out of the commonResult object.
 func (r GetResult) Extract() ([]TenantUser, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
 
 	var resp struct {
 		TenantUsers []TenantUser `json:"users" mapstructure:"users"`
@@ -0,0 +1,180 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rackspace/gophercloud"
+)
+
+// Option configures HTTP transport of provider client created by Authenticate
+type Option func(*clientOptions)
+
+type clientOptions struct {
+	tlsConfig *tls.Config
+	timeout   time.Duration
+	limiter   *RateLimiter
+	retry     *RetryPolicy
+	breaker   *CircuitBreaker
+}
+
+// WithTLSConfig makes provider client use given TLS configuration, e.g. custom CA certificates
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(o *clientOptions) {
+		o.tlsConfig = tlsConfig
+	}
+}
+
+// WithRequestTimeout limits time of a single request, including reading response body.
+// Each retry of the request gets its own timeout, time spent waiting for rate limiter is not included.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(o *clientOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithRateLimiter makes every request sent by provider client, including authentication
+// and re-authentication, wait for a token from given rate limiter
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(o *clientOptions) {
+		o.limiter = limiter
+	}
+}
+
+// WithRetry makes idempotent requests which failed with transient error be retried according to given policy.
+// Each retry waits for rate limiter again.
+func WithRetry(policy RetryPolicy) Option {
+	return func(o *clientOptions) {
+		o.retry = &policy
+	}
+}
+
+// WithCircuitBreaker makes requests fail fast while given circuit breaker is open.
+// Request retried according to retry policy counts as a single failure.
+func WithCircuitBreaker(breaker *CircuitBreaker) Option {
+	return func(o *clientOptions) {
+		o.breaker = breaker
+	}
+}
+
+// newTransport builds transport chain for provider client from given options
+func newTransport(opts ...Option) http.RoundTripper {
+	o := clientOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	transport := http.DefaultTransport
+	if o.tlsConfig != nil {
+		transport = newBaseTransport(o.tlsConfig)
+	}
+	if o.timeout > 0 {
+		transport = &timeoutTransport{timeout: o.timeout, next: transport}
+	}
+	if o.limiter != nil {
+		transport = &limitedTransport{limiter: o.limiter, next: transport}
+	}
+	if o.retry != nil {
+		transport = &retryTransport{policy: *o.retry, next: transport}
+	}
+	if o.breaker != nil {
+		transport = &breakerTransport{breaker: o.breaker, next: transport}
+	}
+
+	return transport
+}
+
+// newBaseTransport returns transport with the same settings as http.DefaultTransport, which uses given TLS configuration
+func newBaseTransport(tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       tlsConfig,
+	}
+}
+
+// withContext returns copy of provider client whose requests are bound to ctx, so they are cancelled when ctx is done.
+// Copies share transport of the original client, which keeps the current token and re-authenticates.
+func withContext(ctx context.Context, provider *gophercloud.ProviderClient) *gophercloud.ProviderClient {
+	client := *provider
+	client.HTTPClient.Transport = &contextTransport{ctx: ctx, next: transportOf(provider)}
+	return &client
+}
+
+// transportOf returns transport used by provider client
+func transportOf(provider *gophercloud.ProviderClient) http.RoundTripper {
+	if provider.HTTPClient.Transport == nil {
+		return http.DefaultTransport
+	}
+	return provider.HTTPClient.Transport
+}
+
+// contextTransport sends requests with context of a single collection
+type contextTransport struct {
+	ctx  context.Context
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.next.RoundTrip(req.WithContext(t.ctx))
+}
+
+// timeoutTransport cancels requests which take longer than timeout
+type timeoutTransport struct {
+	timeout time.Duration
+	next    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// timeout has to cover reading response body, so it is released once body is closed
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases request context when response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close implements io.Closer
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
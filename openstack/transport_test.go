@@ -0,0 +1,320 @@
+// +build small
+
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rackspace/gophercloud"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRetryTransport(t *testing.T) {
+	Convey("Given Keystone which fails first requests with transient errors", t, func() {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch atomic.AddInt32(&calls, 1) {
+			case 1:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case 2:
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			default:
+				w.WriteHeader(http.StatusOK)
+			}
+		}))
+		defer server.Close()
+
+		policy := RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+		client := http.Client{Transport: newTransport(WithRetry(policy))}
+
+		Convey("When GET request is sent", func() {
+			resp, err := client.Get(server.URL)
+
+			Convey("Then request is retried until it succeeds", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				So(atomic.LoadInt32(&calls), ShouldEqual, 3)
+			})
+		})
+
+		Convey("When POST request is sent", func() {
+			resp, err := client.Post(server.URL, "application/json", nil)
+
+			Convey("Then request is not retried", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+				So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given Keystone which asks to retry after longer than maximum delay", t, func() {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		policy := RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+		client := http.Client{Transport: newTransport(WithRetry(policy))}
+
+		Convey("When GET request is sent", func() {
+			resp, err := client.Get(server.URL)
+
+			Convey("Then response is returned without retrying", func() {
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusTooManyRequests)
+				So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+			})
+		})
+	})
+
+	Convey("Given Keystone which answers with client error", t, func() {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		policy := RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+		client := http.Client{Transport: newTransport(WithRetry(policy))}
+
+		Convey("When GET request is sent", func() {
+			_, err := client.Get(server.URL)
+
+			Convey("Then request is not retried", func() {
+				So(err, ShouldBeNil)
+				So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+			})
+		})
+	})
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	Convey("Given Keystone which is down", t, func() {
+		var calls int32
+		healthy := int32(0)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			if atomic.LoadInt32(&healthy) == 0 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		breaker := NewCircuitBreaker(2, 50*time.Millisecond)
+		client := http.Client{Transport: newTransport(WithCircuitBreaker(breaker))}
+
+		Convey("When number of failed requests reaches threshold", func() {
+			client.Get(server.URL)
+			client.Get(server.URL)
+
+			Convey("Then circuit opens and requests fail fast", func() {
+				So(breaker.State(), ShouldEqual, CircuitOpen)
+				_, err := client.Get(server.URL)
+				So(err, ShouldNotBeNil)
+				So(atomic.LoadInt32(&calls), ShouldEqual, 2)
+			})
+
+			Convey("and circuit closes when Keystone recovers after reset timeout", func() {
+				atomic.StoreInt32(&healthy, 1)
+				time.Sleep(60 * time.Millisecond)
+				So(breaker.State(), ShouldEqual, CircuitHalfOpen)
+
+				resp, err := client.Get(server.URL)
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, http.StatusOK)
+				So(breaker.State(), ShouldEqual, CircuitClosed)
+			})
+
+			Convey("and circuit opens again when probe request fails", func() {
+				time.Sleep(60 * time.Millisecond)
+
+				client.Get(server.URL)
+				So(breaker.State(), ShouldEqual, CircuitOpen)
+				So(atomic.LoadInt32(&calls), ShouldEqual, 3)
+			})
+		})
+	})
+}
+
+func TestRequestTimeout(t *testing.T) {
+	Convey("Given Keystone which hangs", t, func() {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}))
+		defer server.Close()
+		defer close(release)
+
+		Convey("When request timeout is set", func() {
+			client := http.Client{Transport: newTransport(WithRequestTimeout(20 * time.Millisecond))}
+
+			start := time.Now()
+			_, err := client.Get(server.URL)
+
+			Convey("Then request fails once timeout passes", func() {
+				So(err, ShouldNotBeNil)
+				So(time.Since(start), ShouldBeLessThan, time.Second)
+			})
+		})
+
+		Convey("When context of the call is cancelled", func() {
+			provider := &gophercloud.ProviderClient{}
+			provider.HTTPClient.Transport = newTransport()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, err := withContext(ctx, provider).Request("GET", server.URL, gophercloud.RequestOpts{})
+
+			Convey("Then in-flight request is cancelled", func() {
+				So(err, ShouldNotBeNil)
+				So(ctx.Err() == context.DeadlineExceeded, ShouldBeTrue)
+				So(time.Since(start), ShouldBeLessThan, time.Second)
+			})
+		})
+	})
+}
+
+func TestSessionTransport(t *testing.T) {
+	Convey("Given provider client whose token has expired", t, func() {
+		keystone := newExpiringKeystone()
+		defer keystone.Close()
+
+		provider, err := Authenticate(context.Background(), keystone.URL+"/", "me", "secret", "tenant", "", "", "")
+		So(err, ShouldBeNil)
+		keystone.expire()
+
+		Convey("When calls rejected with 401 are made at the same time", func() {
+			var done sync.WaitGroup
+			errs := make(chan error, 8)
+			for i := 0; i < 8; i++ {
+				done.Add(1)
+				go func() {
+					defer done.Done()
+					_, err := withContext(context.Background(), provider).Request("GET", keystone.URL+"/v2.0/tenants/t1/users",
+						gophercloud.RequestOpts{})
+					errs <- err
+				}()
+			}
+			done.Wait()
+			close(errs)
+
+			Convey("Then every call succeeds after single re-authentication", func() {
+				for err := range errs {
+					So(err, ShouldBeNil)
+				}
+				So(keystone.authentications(), ShouldEqual, 2)
+			})
+
+			Convey("and token issued by the first authentication is kept by provider", func() {
+				So(provider.TokenID, ShouldEqual, "token-1")
+			})
+		})
+
+		Convey("When re-authentication fails", func() {
+			keystone.Close()
+			_, err := withContext(context.Background(), provider).Request("GET", keystone.URL+"/v2.0/tenants/t1/users",
+				gophercloud.RequestOpts{})
+
+			Convey("Then call fails", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+// expiringKeystone issues new token with every authentication and accepts only the last issued token
+type expiringKeystone struct {
+	*httptest.Server
+	mutex  sync.Mutex
+	token  string
+	issued int
+}
+
+func newExpiringKeystone() *expiringKeystone {
+	k := &expiringKeystone{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"versions": {"values": [{"status": "stable", "id": "v2.0", "links": [{"href": "%s", "rel": "self"}]}]}}`,
+			k.URL+"/v2.0/")
+	})
+	mux.HandleFunc("/v2.0/tokens", func(w http.ResponseWriter, r *http.Request) {
+		k.mutex.Lock()
+		k.issued++
+		k.token = fmt.Sprintf("token-%d", k.issued)
+		token := k.token
+		k.mutex.Unlock()
+
+		fmt.Fprintf(w, `{"access": {"serviceCatalog": [], "token": {"expires": "2016-02-21T14:28:30Z", "id": "%s"}}}`, token)
+	})
+	mux.HandleFunc("/v2.0/tenants/", func(w http.ResponseWriter, r *http.Request) {
+		if !k.accepts(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"users": [{"enabled": true, "id": "u1", "name": "heat"}]}`)
+	})
+	mux.HandleFunc("/v3/users/", func(w http.ResponseWriter, r *http.Request) {
+		if !k.accepts(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"application_credentials": [{"id": "a1", "name": "backup", "expires_at": null}], "links": {"next": null}}`)
+	})
+	k.Server = httptest.NewServer(mux)
+	return k
+}
+
+// accepts checks if request is sent with the last issued token
+func (k *expiringKeystone) accepts(r *http.Request) bool {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	return k.token != "" && r.Header.Get("X-Auth-Token") == k.token
+}
+
+// expire makes Keystone reject all tokens issued so far
+func (k *expiringKeystone) expire() {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.token = ""
+}
+
+// authentications returns number of tokens issued
+func (k *expiringKeystone) authentications() int {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	return k.issued
+}
@@ -0,0 +1,50 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package trusts
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/rackspace/gophercloud"
+)
+
+// Trust represents Keystone v3 OS-TRUST trust
+type Trust struct {
+	ID            string `json:"id" mapstructure:"id"`
+	TrustorUserID string `json:"trustor_user_id" mapstructure:"trustor_user_id"`
+	TrusteeUserID string `json:"trustee_user_id" mapstructure:"trustee_user_id"`
+	ProjectID     string `json:"project_id" mapstructure:"project_id"`
+	Impersonation bool   `json:"impersonation" mapstructure:"impersonation"`
+	// ExpiresAt is empty for trusts which do not expire
+	ExpiresAt string `json:"expires_at" mapstructure:"expires_at"`
+}
+
+// ListResult represents the result of a list operation.
+type ListResult struct {
+	gophercloud.Result
+}
+
+// Extract will get list of trusts out of the ListResult object.
+func (r ListResult) Extract() ([]Trust, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+
+	var resp struct {
+		Trusts []Trust `json:"trusts" mapstructure:"trusts"`
+	}
+
+	err := mapstructure.Decode(r.Body, &resp)
+
+	return resp.Trusts, err
+}
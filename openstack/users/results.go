@@ -0,0 +1,51 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package users
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/rackspace/gophercloud"
+)
+
+// User represents Keystone user
+type User struct {
+	ID           string `json:"id" mapstructure:"id"`
+	Name         string `json:"name" mapstructure:"name"`
+	Username     string `json:"username" mapstructure:"username"`
+	Enabled      bool   `json:"enabled" mapstructure:"enabled"`
+	DomainID     string `json:"domain_id" mapstructure:"domain_id"`
+	LastActiveAt string `json:"last_active_at" mapstructure:"last_active_at"`
+	// PasswordExpiresAt is set only when password expiry is enabled in security compliance
+	PasswordExpiresAt string `json:"password_expires_at" mapstructure:"password_expires_at"`
+}
+
+// ListResult represents the result of a list operation.
+type ListResult struct {
+	gophercloud.Result
+}
+
+// Extract will get list of users out of the ListResult object.
+func (r ListResult) Extract() ([]User, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+
+	var resp struct {
+		Users []User `json:"users" mapstructure:"users"`
+	}
+
+	err := mapstructure.Decode(r.Body, &resp)
+
+	return resp.Users, err
+}
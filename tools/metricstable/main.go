@@ -0,0 +1,60 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// metricstable regenerates table of collected metrics in README from the plugin's metric registry.
+// Usage: go run tools/metricstable/main.go README.md
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/intelsdi-x/snap-plugin-collector-keystone/collector"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: metricstable README.md")
+		os.Exit(2)
+	}
+
+	if err := update(os.Args[1]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// update replaces metrics table between markers in given file
+func update(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	start := bytes.Index(content, []byte(collector.MetricsTableStart))
+	end := bytes.Index(content, []byte(collector.MetricsTableEnd))
+	if start < 0 || end < start {
+		return fmt.Errorf("metrics table markers not found in %s", path)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(content[:start+len(collector.MetricsTableStart)])
+	buf.WriteString("\n")
+	buf.WriteString(collector.MetricsTable())
+	buf.Write(content[end:])
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
@@ -0,0 +1,35 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "time"
+
+// Credential represents OpenStack credential, e.g. ec2, cert or totp
+type Credential struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	UserID    string `json:"user_id"`
+	ProjectID string `json:"project_id"`
+}
+
+// ApplicationCredential represents OpenStack application credential
+type ApplicationCredential struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	UserID    string `json:"user_id"`
+	ProjectID string `json:"project_id"`
+	// ExpiresAt is nil when application credential does not expire
+	ExpiresAt *time.Time `json:"expires_at"`
+}
@@ -0,0 +1,35 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+// RegisteredLimit represents default limit of resource of OpenStack service
+type RegisteredLimit struct {
+	ID           string `json:"id"`
+	ServiceID    string `json:"service_id"`
+	RegionID     string `json:"region_id"`
+	ResourceName string `json:"resource_name"`
+	DefaultLimit int    `json:"default_limit"`
+}
+
+// Limit represents limit of resource of OpenStack service set for single project (or domain)
+type Limit struct {
+	ID            string `json:"id"`
+	ProjectID     string `json:"project_id"`
+	DomainID      string `json:"domain_id"`
+	ServiceID     string `json:"service_id"`
+	RegionID      string `json:"region_id"`
+	ResourceName  string `json:"resource_name"`
+	ResourceLimit int    `json:"resource_limit"`
+}
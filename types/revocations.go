@@ -0,0 +1,35 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "time"
+
+// RevocationEvent represents OpenStack token revocation event
+type RevocationEvent struct {
+	UserID        string `json:"user_id"`
+	ProjectID     string `json:"project_id"`
+	DomainID      string `json:"domain_id"`
+	RoleID        string `json:"role_id"`
+	TrustID       string `json:"trust_id"`
+	ConsumerID    string `json:"consumer_id"`
+	AccessTokenID string `json:"access_token_id"`
+	AuditID       string `json:"audit_id"`
+	AuditChainID  string `json:"audit_chain_id"`
+	// IssuedBefore is the time before which matching tokens were issued
+	IssuedBefore time.Time `json:"issued_before"`
+	// ExpiresAt and RevokedAt are nil when Keystone does not report them
+	ExpiresAt *time.Time `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
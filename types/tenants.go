@@ -16,6 +16,11 @@ package types
 
 // Tenant represents OpenStack tenant
 type Tenant struct {
-	Name string `json:"name"`
-	ID   string
+	Name       string `json:"name"`
+	ID         string
+	DomainID   string `json:"domain_id"`
+	DomainName string `json:"domain_name"`
+	ParentID   string `json:"parent_id"`
+	// Tags are known with authentication API in v3 only
+	Tags []string `json:"tags"`
 }
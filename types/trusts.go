@@ -0,0 +1,28 @@
+/*
+http://www.apache.org/licenses/LICENSE-2.0.txt
+Copyright 2016 Intel Corporation
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import "time"
+
+// Trust represents OpenStack trust delegating roles of trustor to trustee
+type Trust struct {
+	ID            string `json:"id"`
+	TrustorUserID string `json:"trustor_user_id"`
+	TrusteeUserID string `json:"trustee_user_id"`
+	ProjectID     string `json:"project_id"`
+	Impersonation bool   `json:"impersonation"`
+	// ExpiresAt is nil when trust does not expire
+	ExpiresAt *time.Time `json:"expires_at"`
+}
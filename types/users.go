@@ -14,9 +14,16 @@ limitations under the License.
 
 package types
 
+import "time"
+
 // User represents OpenStack user
 type User struct {
-	Name     string `json:"name"`
-	ID       string `json:"id"`
-	Username string `json:"username"`
+	Name         string     `json:"name"`
+	ID           string     `json:"id"`
+	Username     string     `json:"username"`
+	Enabled      bool       `json:"enabled"`
+	DomainID     string     `json:"domain_id"`
+	LastActiveAt *time.Time `json:"last_active_at"`
+	// PasswordExpiresAt is nil when password of user does not expire
+	PasswordExpiresAt *time.Time `json:"password_expires_at"`
 }